@@ -0,0 +1,274 @@
+package easytpl
+
+import (
+	"container/list"
+	"encoding/json"
+	"html/template"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gookit/goutil/errorx"
+)
+
+// DefaultCacheSize default max entries of the built-in memory fragment cache.
+const DefaultCacheSize = 1024
+
+// Cache interface for storage the rendered template fragments. see the template func includeCached
+type Cache interface {
+	// Get the cached value by key
+	Get(key string) (string, bool)
+	// Set the value with ttl. ttl <= 0 means never expire.
+	Set(key, val string, ttl time.Duration)
+	// Delete the value by key
+	Delete(key string)
+}
+
+// WithFragmentCache set the cache for storage rendered fragments.
+func WithFragmentCache(c Cache) OptionFn {
+	return func(r *Renderer) { r.FragmentCache = c }
+}
+
+/*************************************************************
+ * fragment cache on renderer
+ *************************************************************/
+
+// fragmentVers versions of the cached fragments, key is template name.
+// increase version for invalidate all fragments of the template.
+type fragmentVers struct {
+	mu sync.RWMutex
+	// id unique id of the renderer, for prefix the cache keys. the cache may be shared by renderers.
+	id   uint64
+	vers map[string]uint64
+}
+
+// fragmentIDs for generate the unique id of fragmentVers
+var fragmentIDs atomic.Uint64
+
+// fragmentKey build the cache key of the fragment. format: "id/tplName#version:key"
+func (r *Renderer) fragmentKey(tplName, key string) string {
+	name := r.cleanExt(tplName)
+
+	r.fragVers.mu.RLock()
+	id, ver := r.fragVers.id, r.fragVers.vers[name]
+	r.fragVers.mu.RUnlock()
+
+	if id == 0 {
+		r.fragVers.mu.Lock()
+		if r.fragVers.id == 0 {
+			r.fragVers.id = fragmentIDs.Add(1)
+		}
+		id = r.fragVers.id
+		r.fragVers.mu.Unlock()
+	}
+	return strconv.FormatUint(id, 10) + "/" + name + "#" + strconv.FormatUint(ver, 10) + ":" + key
+}
+
+// InvalidateFragment delete the cached fragment of the template by key.
+func (r *Renderer) InvalidateFragment(tplName, key string) {
+	if r.FragmentCache != nil {
+		r.FragmentCache.Delete(r.fragmentKey(tplName, key))
+	}
+}
+
+// InvalidateFragments invalidate all cached fragments of the template.
+func (r *Renderer) InvalidateFragments(tplName string) {
+	name := r.cleanExt(tplName)
+
+	r.fragVers.mu.Lock()
+	defer r.fragVers.mu.Unlock()
+	if r.fragVers.vers == nil {
+		r.fragVers.vers = make(map[string]uint64)
+	}
+	r.fragVers.vers[name]++
+}
+
+// includeCached include other template with data, and cache the rendered result.
+//
+// ttl can be: int seconds, duration string(eg: "5m") or time.Duration. ttl <= 0 means never expire.
+//
+// The push, prepend, section, once and set(string value) in the fragment are cached too,
+// and replayed on cache hit. if set a non-string value, the fragment will not be cached.
+//
+// Usage:
+//
+//	{{ includeCached "layouts/nav" "nav" 300 . }}
+//	{{ includeCached "layouts/footer" (printf "footer:%s" .Lang) "10m" . }}
+func (es *execSet) includeCached(tplName, key string, ttl any, data ...any) (template.HTML, error) {
+	r := es.r
+	dur, err := toDuration(ttl)
+	if err != nil {
+		return "", errorx.Ef("includeCached %q: invalid ttl: %w", tplName, err)
+	}
+	// check before lookup the cache, the fragment may be cached by other renderer.
+	if r.Sandbox && !r.allowInclude(tplName) {
+		return "", errorx.Ef("the include template %q is not allowed on sandbox mode", tplName)
+	}
+
+	st := es.st
+	cacheKey := r.fragmentKey(tplName, key)
+	if s, ok := r.FragmentCache.Get(cacheKey); ok {
+		var frag fragment
+		if err := json.Unmarshal([]byte(s), &frag); err == nil {
+			r.logDebug("easytpl: include the cached fragment", "name", tplName, "key", key)
+			// replay the state changes. eg: push to stacks
+			for _, op := range frag.Ops {
+				st.apply(op)
+			}
+			return frag.HTML, nil
+		}
+	}
+
+	rec := &fragmentRec{}
+	st.recs = append(st.recs, rec)
+	s, err := es.include(tplName, data...)
+	st.recs = st.recs[:len(st.recs)-1]
+	if err != nil {
+		return s, err
+	}
+
+	if rec.skip != "" {
+		r.logDebug("easytpl: skip cache the fragment", "name", tplName, "key", key, "reason", rec.skip)
+		return s, nil
+	}
+
+	bs, err := json.Marshal(fragment{HTML: s, Ops: rec.ops})
+	if err != nil {
+		return s, err
+	}
+	r.FragmentCache.Set(cacheKey, string(bs), dur)
+	return s, nil
+}
+
+// fragment the cached fragment, contains the rendered contents and the state changes.
+type fragment struct {
+	HTML template.HTML `json:"html"`
+	Ops  []fragmentOp  `json:"ops,omitempty"`
+}
+
+// fragmentOp a render state change in the fragment. eg: push to a stack, define a section.
+type fragmentOp struct {
+	// Op is one of: push, prepend, section, once, set
+	Op   string `json:"op"`
+	Name string `json:"name"`
+	Val  string `json:"val,omitempty"`
+}
+
+// fragmentRec record the state changes on render a fragment for cache.
+type fragmentRec struct {
+	ops []fragmentOp
+	// skip the reason of the fragment cannot be cached
+	skip string
+}
+
+func toDuration(ttl any) (time.Duration, error) {
+	switch v := ttl.(type) {
+	case time.Duration:
+		return v, nil
+	case int:
+		return time.Duration(v) * time.Second, nil
+	case int64:
+		return time.Duration(v) * time.Second, nil
+	case string:
+		return time.ParseDuration(v)
+	}
+	return 0, errorx.Ef("unsupported ttl type %T", ttl)
+}
+
+/*************************************************************
+ * built-in memory cache
+ *************************************************************/
+
+// MemoryCache a simple in-memory LRU cache with TTL. it is safe for concurrent use.
+type MemoryCache struct {
+	mu   sync.Mutex
+	size int
+	// lru list, front is the most recently used.
+	lru  *list.List
+	data map[string]*list.Element
+}
+
+type memEntry struct {
+	key    string
+	val    string
+	expire time.Time
+}
+
+// NewMemoryCache create a memory cache. size is max entries, if <= 0 will use DefaultCacheSize.
+func NewMemoryCache(size int) *MemoryCache {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+
+	return &MemoryCache{
+		size: size,
+		lru:  list.New(),
+		data: make(map[string]*list.Element),
+	}
+}
+
+// Get the cached value by key
+func (c *MemoryCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.data[key]
+	if !ok {
+		return "", false
+	}
+
+	ent := el.Value.(*memEntry)
+	if !ent.expire.IsZero() && time.Now().After(ent.expire) {
+		c.remove(el)
+		return "", false
+	}
+
+	c.lru.MoveToFront(el)
+	return ent.val, true
+}
+
+// Set the value with ttl. ttl <= 0 means never expire.
+func (c *MemoryCache) Set(key, val string, ttl time.Duration) {
+	ent := &memEntry{key: key, val: val}
+	if ttl > 0 {
+		ent.expire = time.Now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.data[key]; ok {
+		el.Value = ent
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.data[key] = c.lru.PushFront(ent)
+	// remove the least recently used
+	if c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+// Delete the value by key
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.data[key]; ok {
+		c.remove(el)
+	}
+}
+
+// Len get the number of cached entries
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func (c *MemoryCache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.data, el.Value.(*memEntry).key)
+}
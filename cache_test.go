@@ -0,0 +1,167 @@
+package easytpl_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/gookit/easytpl"
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+func TestRenderer_includeCached(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	var calls int
+	r := easytpl.NewInited(func(r *easytpl.Renderer) {
+		r.AddFunc("counter", func() int {
+			calls++
+			return calls
+		})
+	})
+	r.LoadStrings(map[string]string{
+		"nav":  `nav{{ counter }}:{{ . }}`,
+		"home": `home, {{ includeCached "nav" "nav-key" 60 . }}`,
+		"page": `page, {{ includeCached "nav" (printf "nav:%s" .) "1ms" . }}`,
+	})
+
+	is.NoErr(r.Execute(bf, "home", "tom"))
+	is.Eq("home, nav1:tom", bf.String())
+
+	// from cache
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "home", "john"))
+	is.Eq("home, nav1:tom", bf.String())
+
+	// invalidate by key
+	r.InvalidateFragment("nav", "nav-key")
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "home", "john"))
+	is.Eq("home, nav2:john", bf.String())
+
+	// invalidate by template name
+	r.InvalidateFragments("nav.tpl")
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "home", "tom"))
+	is.Eq("home, nav3:tom", bf.String())
+
+	// ttl expired
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "page", "tom"))
+	is.Eq("page, nav4:tom", bf.String())
+	time.Sleep(2 * time.Millisecond)
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "page", "tom"))
+	is.Eq("page, nav5:tom", bf.String())
+
+	// invalid ttl
+	bf.Reset()
+	err := r.String(bf, `{{ includeCached "nav" "key" "invalid" }}`, nil)
+	is.ErrSubMsg(err, "invalid ttl")
+}
+
+func TestRenderer_includeCached_state(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	var calls int
+	r := easytpl.NewInited(easytpl.WithLayout("layout"), func(r *easytpl.Renderer) {
+		r.AddFunc("counter", func() int {
+			calls++
+			return calls
+		})
+	})
+	r.LoadStrings(map[string]string{
+		"layout": `{{ yield "title" }}|{{ yield }}|{{ stack "js" }}|{{ get "nav" }}`,
+		"nav": `{{ push "js" }}<script src="nav.js"></script>{{ end -}}
+{{ prepend "js" }}<script src="lib.js"></script>{{ end -}}
+{{ once "nav" }}{{ section "title" }}Nav{{ end }}{{ end -}}
+{{ set "nav" "yes" }}nav{{ counter }}`,
+		"home":  `{{ includeCached "nav" "nav" 60 }}`,
+		"about": `{{ includeCached "nav" "nav" 60 }}{{ once "nav" }}twice{{ end }}`,
+	})
+
+	want := `Nav|nav1|<script src="lib.js"></script><script src="nav.js"></script>|yes`
+	is.NoErr(r.Render(bf, "home", nil))
+	is.Eq(want, bf.String())
+
+	// the state changes are replayed on cache hit
+	bf.Reset()
+	is.NoErr(r.Render(bf, "home", nil))
+	is.Eq(want, bf.String())
+	bf.Reset()
+	is.NoErr(r.Render(bf, "about", nil))
+	is.Eq(`Nav|nav1|<script src="lib.js"></script><script src="nav.js"></script>|yes`, bf.String())
+
+	// not cached on set a non-string var
+	r.LoadStrings(map[string]string{
+		"num":  `{{ set "num" 1 }}num{{ counter }}`,
+		"page": `{{ includeCached "num" "num" 60 }}`,
+	})
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "page", nil))
+	is.Eq("num2", bf.String())
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "page", nil))
+	is.Eq("num3", bf.String())
+}
+
+func TestRenderer_includeCached_shared(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+	cache := easytpl.NewMemoryCache(10)
+
+	newRenderer := func(nav string, fns ...easytpl.OptionFn) *easytpl.Renderer {
+		r := easytpl.NewInited(append(fns, easytpl.WithFragmentCache(cache))...)
+		r.LoadStrings(map[string]string{
+			"nav":  nav,
+			"home": `home, {{ includeCached "nav" "key" 60 }}`,
+		})
+		return r
+	}
+
+	r1 := newRenderer("nav1")
+	is.NoErr(r1.Execute(bf, "home", nil))
+	is.Eq("home, nav1", bf.String())
+
+	// the cache keys are prefixed per renderer
+	r2 := newRenderer("nav2")
+	bf.Reset()
+	is.NoErr(r2.Execute(bf, "home", nil))
+	is.Eq("home, nav2", bf.String())
+
+	// sandbox check before lookup the cache
+	r3 := newRenderer("nav3", easytpl.WithSandbox())
+	bf.Reset()
+	err := r3.Execute(bf, "home", nil)
+	is.ErrSubMsg(err, `the include template "nav" is not allowed on sandbox mode`)
+}
+
+func TestMemoryCache(t *testing.T) {
+	is := assert.New(t)
+	c := easytpl.NewMemoryCache(2)
+
+	c.Set("a", "A", 0)
+	c.Set("b", "B", time.Minute)
+	val, ok := c.Get("a")
+	is.True(ok)
+	is.Eq("A", val)
+
+	// "b" is the least recently used
+	c.Set("c", "C", 0)
+	is.Eq(2, c.Len())
+	_, ok = c.Get("b")
+	is.False(ok)
+
+	c.Set("a", "A1", time.Millisecond)
+	val, ok = c.Get("a")
+	is.True(ok)
+	is.Eq("A1", val)
+	time.Sleep(2 * time.Millisecond)
+	_, ok = c.Get("a")
+	is.False(ok)
+
+	c.Delete("c")
+	is.Eq(0, c.Len())
+}
@@ -0,0 +1,47 @@
+package easytpl
+
+import "context"
+
+// ComposerFunc the view composer func, can augment the data of the template. returns the new data.
+type ComposerFunc func(ctx context.Context, name string, data any) any
+
+type composer struct {
+	pattern string
+	fn      ComposerFunc
+}
+
+// Compose add a view composer for the templates match the pattern. the fn will
+// run before the matched template, layout or include is executed, and can augment its data.
+//
+// Pattern: see SandboxIncludes. eg: "layouts/header", "admin/*", "admin/**"
+//
+// NOTE: please add composers before rendering, it is not safe for concurrent use.
+//
+// Usage:
+//
+//	r.Compose("layouts/header", func(ctx context.Context, name string, data any) any {
+//		mp, _ := data.(map[string]any)
+//		if mp == nil {
+//			mp = make(map[string]any)
+//		}
+//		mp["menus"] = loadMenus(ctx)
+//		return mp
+//	})
+func (r *Renderer) Compose(pattern string, fn ComposerFunc) {
+	r.composers = append(r.composers, composer{pattern: r.cleanExt(pattern), fn: fn})
+}
+
+// compose run the matched composers for the template, returns the new data.
+func (r *Renderer) compose(ctx context.Context, tplName string, data any) any {
+	if len(r.composers) == 0 {
+		return data
+	}
+
+	name := r.cleanExt(tplName)
+	for _, c := range r.composers {
+		if matchTplName(c.pattern, name) {
+			data = c.fn(ctx, name, data)
+		}
+	}
+	return data
+}
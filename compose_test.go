@@ -0,0 +1,44 @@
+package easytpl_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/gookit/easytpl"
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+func TestRenderer_Compose(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewInited()
+	r.LoadStrings(map[string]string{
+		"layout":         `{{ include "layouts/header" }}|{{ yield }}|{{ .user }}`,
+		"layouts/header": `header: {{ .menus }}`,
+		"admin/home":     `home: {{ .user }}, {{ .unread }}`,
+		"admin/sub/one":  `one: {{ .unread }}`,
+	})
+
+	var names []string
+	r.Compose("layouts/header.tpl", func(ctx context.Context, name string, data any) any {
+		names = append(names, name)
+		return map[string]any{"menus": []string{"a", "b"}}
+	})
+	r.Compose("admin/*", func(ctx context.Context, name string, data any) any {
+		names = append(names, name)
+		mp := data.(map[string]any)
+		mp["unread"] = 3
+		return mp
+	})
+
+	is.NoErr(r.Render(bf, "admin/home", map[string]any{"user": "tom"}, "layout"))
+	is.Eq("header: [a b]|home: tom, 3|tom", bf.String())
+	is.Eq([]string{"admin/home", "layouts/header"}, names)
+
+	// not match sub dir
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "admin/sub/one", map[string]any{}))
+	is.Eq("one: ", bf.String())
+}
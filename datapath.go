@@ -0,0 +1,344 @@
+package easytpl
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+
+	"github.com/gookit/goutil/errorx"
+)
+
+// rangeElem the path segment for the range element. eg: "Items[]" -> ["Items", "[]"]
+const rangeElem = "[]"
+
+// dataRef a data path reference in the template
+type dataRef struct {
+	// path from the root data. eg: ["Items", "[]", "Price"]
+	path []string
+	tree *parse.Tree
+	node parse.Node
+}
+
+// pathString format the path. eg: ".Items[].Price"
+func pathString(path []string) string {
+	var sb strings.Builder
+	for _, seg := range path {
+		if seg != rangeElem {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(seg)
+	}
+
+	if sb.Len() == 0 {
+		return "."
+	}
+	return sb.String()
+}
+
+// refWalker collect the data path references by walking the parse tree.
+//
+// The dot path is tracked on walk into with and range blocks, and the variables
+// declared by the pipelines. nil path means unknown. eg: the dot is a func result.
+type refWalker struct {
+	refs []dataRef
+	// lookup the called template tree. for {{ template "name" . }}
+	lookup func(name string) *parse.Tree
+	// lookupInclude lookup the included template tree. for {{ include "name" . }}, can be nil.
+	lookupInclude func(name string) *parse.Tree
+	// visiting templates, for avoid infinite recursion. key is "name:dot path"
+	visiting map[string]bool
+}
+
+// walkTree walk the template tree with the dot path
+func (w *refWalker) walkTree(tree *parse.Tree, dot []string) {
+	if tree == nil || tree.Root == nil {
+		return
+	}
+
+	key := tree.Name + ":" + pathString(dot)
+	if dot == nil {
+		key = tree.Name + ":?"
+	}
+	if w.visiting[key] {
+		return
+	}
+
+	if w.visiting == nil {
+		w.visiting = make(map[string]bool)
+	}
+	w.visiting[key] = true
+	defer delete(w.visiting, key)
+
+	vars := map[string][]string{"$": dot}
+	w.walk(tree, tree.Root, dot, vars)
+}
+
+func (w *refWalker) walk(tree *parse.Tree, node parse.Node, dot []string, vars map[string][]string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, sub := range n.Nodes {
+			w.walk(tree, sub, dot, vars)
+		}
+	case *parse.ActionNode:
+		w.walkPipe(tree, n.Pipe, dot, vars)
+		w.declare(n.Pipe, w.pipePath(n.Pipe, dot, vars), vars)
+	case *parse.IfNode:
+		w.walkBranch(tree, &n.BranchNode, dot, dot, vars)
+	case *parse.WithNode:
+		w.walkBranch(tree, &n.BranchNode, w.pipePath(n.Pipe, dot, vars), dot, vars)
+	case *parse.RangeNode:
+		var elem []string
+		if p := w.pipePath(n.Pipe, dot, vars); p != nil {
+			elem = append(append([]string{}, p...), rangeElem)
+			w.refs = append(w.refs, dataRef{path: elem, tree: tree, node: n})
+		}
+		w.walkBranch(tree, &n.BranchNode, elem, dot, vars)
+	case *parse.TemplateNode:
+		var arg []string
+		if n.Pipe != nil {
+			w.walkPipe(tree, n.Pipe, dot, vars)
+			arg = w.pipePath(n.Pipe, dot, vars)
+		}
+		if w.lookup != nil {
+			w.walkTree(w.lookup(n.Name), arg)
+		}
+	}
+}
+
+// walkBranch walk the if/with/range branch. inner is the dot path of the body, outer for the else.
+func (w *refWalker) walkBranch(tree *parse.Tree, n *parse.BranchNode, inner, outer []string, vars map[string][]string) {
+	w.walkPipe(tree, n.Pipe, outer, vars)
+
+	// the variables are scoped to the block
+	sub := copyVars(vars)
+	if n.NodeType == parse.NodeRange && len(n.Pipe.Decl) > 0 {
+		// {{ range $i, $v := .Items }} or {{ range $v := .Items }}
+		sub[n.Pipe.Decl[len(n.Pipe.Decl)-1].Ident[0]] = inner
+		if len(n.Pipe.Decl) == 2 {
+			sub[n.Pipe.Decl[0].Ident[0]] = nil
+		}
+	} else {
+		w.declare(n.Pipe, w.pipePath(n.Pipe, outer, vars), sub)
+	}
+
+	w.walk(tree, n.List, inner, sub)
+	if n.ElseList != nil {
+		w.walk(tree, n.ElseList, outer, copyVars(vars))
+	}
+}
+
+func (w *refWalker) walkPipe(tree *parse.Tree, pipe *parse.PipeNode, dot []string, vars map[string][]string) {
+	if pipe == nil {
+		return
+	}
+
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			switch a := arg.(type) {
+			case *parse.FieldNode:
+				w.addRef(tree, a, dot, a.Ident)
+			case *parse.VariableNode:
+				if len(a.Ident) > 1 {
+					w.addRef(tree, a, vars[a.Ident[0]], a.Ident[1:])
+				}
+			case *parse.ChainNode:
+				if p, ok := a.Node.(*parse.PipeNode); ok {
+					w.walkPipe(tree, p, dot, vars)
+				}
+			case *parse.PipeNode:
+				w.walkPipe(tree, a, dot, vars)
+			}
+		}
+
+		// follow the include template. eg: {{ include "name" .User }}
+		if w.lookupInclude != nil && isFuncCall(cmd, "include", "includeCached") {
+			w.walkInclude(cmd, dot, vars)
+		}
+	}
+}
+
+func (w *refWalker) walkInclude(cmd *parse.CommandNode, dot []string, vars map[string][]string) {
+	name, ok := stringArg(cmd, 1)
+	if !ok {
+		return
+	}
+
+	// include "name" [data], includeCached "name" key ttl [data]
+	dataIdx := 2
+	if isFuncCall(cmd, "includeCached") {
+		dataIdx = 4
+	}
+
+	var arg []string
+	if dataIdx < len(cmd.Args) {
+		arg = w.argPath(cmd.Args[dataIdx], dot, vars)
+	}
+	w.walkTree(w.lookupInclude(name), arg)
+}
+
+func (w *refWalker) addRef(tree *parse.Tree, node parse.Node, base, fields []string) {
+	if base == nil {
+		return
+	}
+
+	path := make([]string, 0, len(base)+len(fields))
+	path = append(append(path, base...), fields...)
+	w.refs = append(w.refs, dataRef{path: path, tree: tree, node: node})
+}
+
+// pipePath get the result data path of the pipeline. nil on unknown.
+func (w *refWalker) pipePath(pipe *parse.PipeNode, dot []string, vars map[string][]string) []string {
+	if pipe == nil || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return nil
+	}
+	return w.argPath(pipe.Cmds[0].Args[0], dot, vars)
+}
+
+// argPath get the data path of the argument node. nil on unknown.
+func (w *refWalker) argPath(arg parse.Node, dot []string, vars map[string][]string) []string {
+	var base, fields []string
+	switch a := arg.(type) {
+	case *parse.DotNode:
+		base = dot
+	case *parse.FieldNode:
+		base, fields = dot, a.Ident
+	case *parse.VariableNode:
+		base, fields = vars[a.Ident[0]], a.Ident[1:]
+	case *parse.PipeNode:
+		return w.pipePath(a, dot, vars)
+	default:
+		return nil
+	}
+
+	if base == nil {
+		return nil
+	}
+	return append(append([]string{}, base...), fields...)
+}
+
+// declare the variables of the pipeline. eg: {{ $name := .User.Name }}
+func (w *refWalker) declare(pipe *parse.PipeNode, path []string, vars map[string][]string) {
+	if pipe == nil {
+		return
+	}
+	for _, v := range pipe.Decl {
+		vars[v.Ident[0]] = path
+	}
+}
+
+func copyVars(vars map[string][]string) map[string][]string {
+	mp := make(map[string][]string, len(vars))
+	for k, v := range vars {
+		mp[k] = v
+	}
+	return mp
+}
+
+/*************************************************************
+ * data references API
+ *************************************************************/
+
+// DataRef a data path reference in the template.
+type DataRef struct {
+	// Path the data path from the render data. eg: ".User.Name", ".Items[].Price" in range body.
+	Path string `json:"path"`
+	// Template name of the reference in. eg: "user/profile", "layouts/header"
+	Template string `json:"template"`
+	// File the source file path of the template. empty on loaded from string.
+	File string `json:"file,omitempty"`
+	Line int    `json:"line"`
+	Col  int    `json:"col"`
+}
+
+// DataRefs the data path references of a template.
+type DataRefs []DataRef
+
+// Paths get the unique data paths, sorted.
+func (refs DataRefs) Paths() []string {
+	seen := make(map[string]bool, len(refs))
+	paths := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if !seen[ref.Path] {
+			seen[ref.Path] = true
+			paths = append(paths, ref.Path)
+		}
+	}
+
+	sort.Strings(paths)
+	return paths
+}
+
+// JSON encode the references to JSON
+func (refs DataRefs) JSON() ([]byte, error) {
+	return json.MarshalIndent(refs, "", "  ")
+}
+
+// DataRefs walk the template parse tree(including its includes, blocks and extends base),
+// returns the data paths it references, sorted by path and location.
+//
+// Usage:
+//
+//	refs, err := r.DataRefs("user/profile")
+//	paths := refs.Paths() // [".Items", ".Items[]", ".Items[].Price", ".User.Name"]
+//	bs, err := refs.JSON()
+func (r *Renderer) DataRefs(name string) (DataRefs, error) {
+	tpl := r.master(name)
+	if tpl == nil {
+		return nil, errorx.Ef("easytpl: the template %q is not found", name)
+	}
+
+	lookup := func(name string) *parse.Tree {
+		if t := tpl.Lookup(name); t != nil {
+			return t.Tree
+		}
+		if t := r.master(name); t != nil {
+			return t.Tree
+		}
+		return nil
+	}
+
+	w := &refWalker{lookup: lookup, lookupInclude: lookup}
+	w.walkTree(tpl.Tree, []string{})
+
+	refs := make(DataRefs, 0, len(w.refs))
+	seen := make(map[DataRef]bool, len(w.refs))
+	for _, ref := range w.refs {
+		dr := DataRef{
+			Path:     pathString(ref.path),
+			Template: ref.tree.Name,
+			File:     r.fileOf(ref.tree.ParseName),
+		}
+
+		// location format: "name:line:col"
+		loc, _ := ref.tree.ErrorContext(ref.node)
+		if nodes := strings.Split(loc, ":"); len(nodes) >= 3 {
+			dr.Line, _ = strconv.Atoi(nodes[len(nodes)-2])
+			dr.Col, _ = strconv.Atoi(nodes[len(nodes)-1])
+		}
+
+		if !seen[dr] {
+			seen[dr] = true
+			refs = append(refs, dr)
+		}
+	}
+
+	sort.SliceStable(refs, func(i, j int) bool {
+		a, b := refs[i], refs[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Template != b.Template {
+			return a.Template < b.Template
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Col < b.Col
+	})
+	return refs, nil
+}
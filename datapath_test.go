@@ -0,0 +1,54 @@
+package easytpl_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gookit/easytpl"
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+func TestRenderer_DataRefs(t *testing.T) {
+	is := assert.New(t)
+	dir := t.TempDir()
+
+	r := easytpl.NewExtends()
+	r.LoadStrings(map[string]string{
+		"base": `{{ block "title" . }}{{ .Site }}{{ end }}
+{{ block "body" . }}{{ end }}`,
+		"header": `{{ .Name }}`,
+	})
+	ordersFile := filepath.Join(dir, "orders.tpl")
+	is.NoErr(os.WriteFile(ordersFile, []byte(`{{ extends "base" }}
+{{ define "body" }}{{ include "header" .User }}
+{{ range .Items }}{{ .Price }}{{ $.User.Name }}{{ end }}{{ end }}`), 0644))
+	r.LoadFile("orders", ordersFile)
+
+	file := filepath.Join(dir, "user.tpl")
+	is.NoErr(os.WriteFile(file, []byte("{{ layout \"none\" }}\nhi\n {{ .Name }}"), 0644))
+	r.LoadFile("user", file)
+
+	refs, err := r.DataRefs("orders")
+	is.NoErr(err)
+	is.Eq([]string{".Items", ".Items[]", ".Items[].Price", ".Site", ".User", ".User.Name"}, refs.Paths())
+
+	// the block defined in the extends template, the extends line is kept
+	is.Eq(easytpl.DataRef{Path: ".Items[].Price", Template: "body", File: ordersFile, Line: 3, Col: 21}, refs[2])
+	// from the base template
+	is.Eq(easytpl.DataRef{Path: ".Site", Template: "title", Line: 1, Col: 24}, refs[3])
+	// from include
+	is.Eq(easytpl.DataRef{Path: ".User.Name", Template: "header", Line: 1, Col: 3}, refs[6])
+
+	bs, err := refs.JSON()
+	is.NoErr(err)
+	is.StrContains(string(bs), `"path": ".Site",`)
+
+	// the layout directive line is kept
+	refs, err = r.DataRefs("user")
+	is.NoErr(err)
+	is.Eq(easytpl.DataRefs{{Path: ".Name", Template: "user", File: file, Line: 3, Col: 4}}, refs)
+
+	_, err = r.DataRefs("not-exist")
+	is.Err(err)
+}
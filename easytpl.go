@@ -12,6 +12,7 @@ package easytpl
 import (
 	"fmt"
 	"html/template"
+	"log/slog"
 )
 
 // DefaultExt name
@@ -31,17 +32,45 @@ type TplDelims struct {
 var builtInFuncMap = template.FuncMap{
 	// don't escape content
 	"raw": func(s string) template.HTML { return template.HTML(s) },
+	// mark content as trusted for the special context
+	"safeURL":  func(s string) template.URL { return template.URL(s) },
+	"safeJS":   func(s string) template.JS { return template.JS(s) },
+	"safeCSS":  func(s string) template.CSS { return template.CSS(s) },
+	"safeAttr": func(s string) template.HTMLAttr { return template.HTMLAttr(s) },
+	// html helper funcs
+	"nl2br":        nl2br,
+	"stripTags":    stripTags,
+	"truncateHTML": truncateHTML,
+	"classNames":   classNames,
+	"attrs":        attrs,
 	// add some empty func for resolve compile error
-	"yield": func() (string, error) {
+	"yield": func(...any) (string, error) {
 		return "", fmt.Errorf("yield called with no layout defined")
 	},
 	"current_tpl": func() string { return "" },
+	"includeFrom": func(string, string, ...any) (template.HTML, error) {
+		return "", fmt.Errorf("includeFrom called out of render")
+	},
+	"push":       func(string) (bool, error) { return false, fmt.Errorf("push called out of render") },
+	"prepend":    func(string) (bool, error) { return false, fmt.Errorf("prepend called out of render") },
+	"once":       func(string) bool { return true },
+	"stack":      func(string) template.HTML { return "" },
+	"section":    func(string) (bool, error) { return false, fmt.Errorf("section called out of render") },
+	"hasSection": func(string) bool { return false },
+	"set":        func(string, any) string { return "" },
+	"global":     func(string) any { return nil },
+	"get":        func(string, ...any) any { return nil },
+	// internal func for end the push/prepend block
+	endCaptureFuncName: func() bool { return false },
 }
 
 // Options for renderer
 type Options struct {
-	// Debug mode for development.
+	// Debug mode for development. will log the debug events to the Logger.
 	Debug bool
+	// Logger for log the structured events. eg: load files, render errors.
+	// default is nil, will not log anything.
+	Logger *slog.Logger
 	// Delims define for template. default is "{{", "}}"
 	Delims TplDelims
 	// ViewsDir the default views directory, multi dirs use "," split
@@ -55,6 +84,11 @@ type Options struct {
 	Layout string
 	// DisableLayout disable apply layout render. default is False
 	DisableLayout bool
+	// LayoutParents the parent layout of the layouts, for nested layouts.
+	// key is layout name, value is parent layout name.
+	//
+	// It will override the directive in the layout file. eg: {{ layout "site/layout" }}
+	LayoutParents map[string]string
 
 	// EnableExtends enable extends feature. default is False
 	EnableExtends bool
@@ -67,9 +101,42 @@ type Options struct {
 	// 	{{ extends "base" }}
 	// 	{{ define "body" }} ... {{ end }}
 	ExtendsBase map[string]string
-	// AutoSearchFile
-	// TODO: auto search template file, when not found on compiled templates. default is False
+	// Loader custom templates loader. eg: load templates from database.
+	// the templates are loaded from it on initialize, see Loader
+	Loader Loader
+	// AutoSearchFile lazy load the template by the Loader, when not found on loaded templates. default is False
 	AutoSearchFile bool
+
+	// Sandbox mode for render untrusted templates. default is False
+	//
+	// On sandbox mode:
+	// 	- will remove OS, environment and file access funcs. see tplfunc.SafeFuncMap()
+	// 	- only can include the templates that match SandboxIncludes
+	// 	- only can call the templates defined in the same text by {{ template "name" }}
+	Sandbox bool
+	// SandboxIncludes allowed include template names on sandbox mode. default is empty, not allow include.
+	//
+	// Support name patterns:
+	// 	- exact name: "partials/footer"
+	// 	- glob pattern: "partials/*" - match "partials/footer", not match "partials/sub/footer"
+	// 	- namespace: "partials/**" - match all templates under "partials/"
+	SandboxIncludes []string
+
+	// Limits for execute templates, can be overridden on each render by ContextWithLimits().
+	Limits Limits
+	// GlobalData the global shared data, accessible in every template, layout and include.
+	//
+	// Usage in template:
+	// 	- by func: {{ global "siteName" }}
+	// 	- by data: {{ $.Global.siteName }} - only when the render data is an M or map[string]any.
+	// 	  if the render data already has the key "Global", it will not be overridden.
+	GlobalData map[string]any
+	// FragmentCache for storage the rendered fragments of includeCached. default is a MemoryCache
+	FragmentCache Cache
+	// Minify the rendered HTML output. will collapse whitespace and strip comments. default is False
+	//
+	// The contents of <pre>, <textarea>, <script> and <style> are kept intact.
+	Minify bool
 }
 
 // OptionFn for renderer
@@ -100,6 +167,11 @@ func NewInitialized(fns ...OptionFn) *Renderer {
 // WithDebug set enable debug mode.
 func WithDebug(r *Renderer) { r.Debug = true }
 
+// WithLogger set the logger for log the structured events.
+func WithLogger(l *slog.Logger) OptionFn {
+	return func(r *Renderer) { r.Logger = l }
+}
+
 // WithLayout set the layout template name.
 func WithLayout(layoutName string) OptionFn {
 	return func(r *Renderer) {
@@ -117,6 +189,27 @@ func DisableLayout(r *Renderer) {
 // EnableExtends enable extends feature.
 func EnableExtends(r *Renderer) { r.EnableExtends = true }
 
+// WithLoader set the templates loader.
+func WithLoader(l Loader) OptionFn {
+	return func(r *Renderer) { r.Loader = l }
+}
+
+// WithMinify enable minify the rendered HTML output.
+func WithMinify(r *Renderer) { r.Minify = true }
+
+// WithGlobalData set the global shared data.
+func WithGlobalData(data map[string]any) OptionFn {
+	return func(r *Renderer) { r.GlobalData = data }
+}
+
+// WithSandbox enable sandbox mode and set allowed include template names.
+func WithSandbox(includes ...string) OptionFn {
+	return func(r *Renderer) {
+		r.Sandbox = true
+		r.SandboxIncludes = append(r.SandboxIncludes, includes...)
+	}
+}
+
 // WithTplDirs set template dirs
 func WithTplDirs(dirs string) OptionFn {
 	return func(r *Renderer) { r.ViewsDir = dirs }
@@ -0,0 +1,124 @@
+package easytpltest
+
+import (
+	"strings"
+)
+
+// diffContext the number of unchanged lines around the changes
+const diffContext = 2
+
+// Diff build a readable line diff of the want and got. format:
+//
+//	--- want
+//	+++ got
+//	  unchanged line
+//	- want line
+//	+ got line
+func Diff(want, got string) string {
+	a, b := strings.Split(want, "\n"), strings.Split(got, "\n")
+
+	// trim the common prefix and suffix lines, only diff the middle lines
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	lines := make([]diffLine, 0, len(a)+len(b)-pre-suf)
+	for _, s := range a[:pre] {
+		lines = append(lines, diffLine{' ', s})
+	}
+	lines = append(lines, diffLines(a[pre:len(a)-suf], b[pre:len(b)-suf])...)
+	for _, s := range a[len(a)-suf:] {
+		lines = append(lines, diffLine{' ', s})
+	}
+
+	// only output the changed lines with context
+	var sb strings.Builder
+	sb.WriteString("--- want\n+++ got\n")
+
+	last := -1
+	for k, ln := range lines {
+		if ln.op == ' ' && !nearChange(lines, k) {
+			continue
+		}
+		if last >= 0 && k > last+1 {
+			sb.WriteString("...\n")
+		}
+
+		sb.WriteByte(ln.op)
+		sb.WriteByte(' ')
+		sb.WriteString(ln.text)
+		sb.WriteByte('\n')
+		last = k
+	}
+	return sb.String()
+}
+
+// maxLCSCells the max cells of the LCS table, avoid use too much memory on diff large contents.
+const maxLCSCells = 1 << 22
+
+// diffLines diff the lines by LCS. if the contents are too large, all lines are reported as changed.
+func diffLines(a, b []string) []diffLine {
+	lines := make([]diffLine, 0, len(a)+len(b))
+	if (len(a)+1)*(len(b)+1) > maxLCSCells {
+		for _, s := range a {
+			lines = append(lines, diffLine{'-', s})
+		}
+		for _, s := range b {
+			lines = append(lines, diffLine{'+', s})
+		}
+		return lines
+	}
+
+	// lcs[i][j] is the length of the longest common lines of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	return lines
+}
+
+// diffLine a line of the diff. op is ' ', '-' or '+'
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// nearChange check there is a changed line in the context of the line k.
+func nearChange(lines []diffLine, k int) bool {
+	for n := max(0, k-diffContext); n <= min(len(lines)-1, k+diffContext); n++ {
+		if lines[n].op != ' ' {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+// Package easytpltest provide golden file testing helpers for the easytpl templates.
+//
+// Render a template and compare the result with the golden file "testdata/<name>.golden",
+// run the tests with flag -easytpltest.update for rewrite the golden files:
+//
+//	go test ./views/... -easytpltest.update
+//
+// If the test package defined a bool flag "update", it also can be used. eg: go test ./views/... -update
+//
+// Usage:
+//
+//	func TestHome(t *testing.T) {
+//		r := easytpl.NewInited(easytpl.WithTplDirs("views"))
+//		easytpltest.Golden(t, r, "home", map[string]any{"name": "tom"}, easytpltest.WithMode(easytpltest.HTML))
+//	}
+package easytpltest
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gookit/easytpl"
+)
+
+// update flag for rewrite the golden files. use a package specific name, avoid conflict with the test package.
+var update = flag.Bool("easytpltest.update", false, "update the golden files of easytpltest")
+
+// needUpdate check the golden files should be rewritten, by the flag -easytpltest.update or -update
+func needUpdate() bool {
+	if *update {
+		return true
+	}
+
+	// the "update" flag defined by the test package
+	if f := flag.Lookup("update"); f != nil {
+		if g, ok := f.Value.(flag.Getter); ok {
+			b, _ := g.Get().(bool)
+			return b
+		}
+	}
+	return false
+}
+
+// Mode the compare mode of the render result and the golden file.
+type Mode uint8
+
+// compare modes
+const (
+	// Exact compare the contents exactly
+	Exact Mode = iota
+	// Whitespace compare after normalize the whitespace: trim lines, collapse spaces and skip blank lines.
+	Whitespace
+	// HTML compare after normalize the HTML: minify it, then one tag per line. see easytpl.MinifyHTML()
+	HTML
+)
+
+// Config for the golden file testing
+type Config struct {
+	// Mode the compare mode. default is Exact
+	Mode Mode
+	// Dir the golden files dir. default is "testdata"
+	Dir string
+	// File the golden file path. default is "<Dir>/<template name>.golden"
+	File string
+	// Layout for render the template. default use the layout of the renderer.
+	//
+	// Set to empty string for disable layout, nil to use the renderer layout.
+	Layout *string
+}
+
+// Option func for the Config
+type Option func(c *Config)
+
+// WithMode set the compare mode
+func WithMode(mode Mode) Option {
+	return func(c *Config) { c.Mode = mode }
+}
+
+// WithFile set the golden file path
+func WithFile(file string) Option {
+	return func(c *Config) { c.File = file }
+}
+
+// WithLayout set the layout for render. empty string for disable layout.
+func WithLayout(layout string) Option {
+	return func(c *Config) { c.Layout = &layout }
+}
+
+// Golden render the template with data, compare the result with the golden file.
+//
+// The golden file will be rewritten on run tests with flag -easytpltest.update.
+func Golden(t testing.TB, r *easytpl.Renderer, name string, data any, opts ...Option) {
+	t.Helper()
+
+	c := &Config{Dir: "testdata"}
+	for _, fn := range opts {
+		fn(c)
+	}
+	if c.File == "" {
+		c.File = filepath.Join(c.Dir, filepath.FromSlash(name)+".golden")
+	}
+
+	var layout []string
+	if c.Layout != nil {
+		layout = []string{*c.Layout}
+	}
+
+	buf := new(bytes.Buffer)
+	if err := r.Render(buf, name, data, layout...); err != nil {
+		t.Fatalf("easytpltest: render the template %q failed: %v", name, err)
+		return
+	}
+
+	AssertGolden(t, c.File, buf.Bytes(), c.Mode)
+}
+
+// AssertGolden compare the contents with the golden file by the mode, report a readable diff on mismatch.
+//
+// The golden file will be rewritten on run tests with flag -easytpltest.update.
+func AssertGolden(t testing.TB, file string, got []byte, mode Mode) {
+	t.Helper()
+
+	if needUpdate() {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatalf("easytpltest: create the golden dir failed: %v", err)
+			return
+		}
+		if err := os.WriteFile(file, got, 0644); err != nil {
+			t.Fatalf("easytpltest: update the golden file failed: %v", err)
+			return
+		}
+		t.Logf("easytpltest: updated the golden file %s", file)
+		return
+	}
+
+	want, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("easytpltest: read the golden file failed: %v\n(run the tests with -easytpltest.update for create it)", err)
+		return
+	}
+
+	wantS, gotS := Normalize(want, mode), Normalize(got, mode)
+	if wantS != gotS {
+		t.Errorf("easytpltest: the result is not match the golden file %s\n(run the tests with -easytpltest.update for rewrite it)\n%s",
+			file, Diff(wantS, gotS))
+	}
+}
+
+var (
+	spaceRe = regexp.MustCompile(`[ \t\r\f\v]+`)
+	tagRe   = regexp.MustCompile(`>\s*<`)
+)
+
+// Normalize the contents by the compare mode.
+func Normalize(src []byte, mode Mode) string {
+	switch mode {
+	case Whitespace:
+		return normalizeSpace(string(src))
+	case HTML:
+		s := string(easytpl.MinifyHTML(src))
+		// one tag per line, for the readable diff
+		return normalizeSpace(tagRe.ReplaceAllString(s, ">\n<"))
+	default:
+		return string(src)
+	}
+}
+
+func normalizeSpace(s string) string {
+	lines := strings.Split(s, "\n")
+	ss := lines[:0]
+	for _, line := range lines {
+		if line = strings.TrimSpace(spaceRe.ReplaceAllString(line, " ")); line != "" {
+			ss = append(ss, line)
+		}
+	}
+	return strings.Join(ss, "\n")
+}
@@ -0,0 +1,133 @@
+package easytpltest_test
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gookit/easytpl"
+	"github.com/gookit/easytpl/easytpltest"
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+// the test package can define its own "update" flag
+var update = flag.Bool("update", false, "update the golden files")
+
+// fakeT record the failures
+type fakeT struct {
+	testing.TB
+	errs []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.errs = append(t.errs, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) Fatalf(format string, args ...any) {
+	t.errs = append(t.errs, fmt.Sprintf(format, args...))
+}
+
+func newRenderer() *easytpl.Renderer {
+	r := easytpl.NewInited(easytpl.WithLayout("layout"))
+	r.LoadStrings(map[string]string{
+		"layout":       `<div class="user">{{ yield }}</div>`,
+		"user/profile": "\n  <h1>{{ .name }}</h1>  <p>age: {{ .age }}</p>\n",
+		"hello":        "hello tom\n welcome",
+	})
+	return r
+}
+
+func TestGolden(t *testing.T) {
+	r := newRenderer()
+	data := map[string]any{"name": "tom", "age": 20}
+
+	easytpltest.Golden(t, r, "user/profile", data, easytpltest.WithMode(easytpltest.HTML))
+	easytpltest.Golden(t, r, "hello", nil, easytpltest.WithLayout(""), easytpltest.WithMode(easytpltest.Whitespace))
+
+	// mismatch
+	ft := &fakeT{TB: t}
+	data["age"] = 22
+	easytpltest.Golden(ft, r, "user/profile", data, easytpltest.WithMode(easytpltest.HTML))
+	assert.Len(t, ft.errs, 1)
+	assert.StrContains(t, ft.errs[0], "the result is not match the golden file "+filepath.Join("testdata", "user", "profile.golden"))
+	assert.StrContains(t, ft.errs[0], "- <p>age: 20</p>\n+ <p>age: 22</p>")
+
+	// exact mode
+	ft = &fakeT{TB: t}
+	easytpltest.Golden(ft, r, "hello", nil, easytpltest.WithLayout(""))
+	assert.Len(t, ft.errs, 1)
+
+	// not found
+	ft = &fakeT{TB: t}
+	easytpltest.Golden(ft, r, "hello", nil, easytpltest.WithFile("testdata/not-exists.golden"))
+	assert.Len(t, ft.errs, 1)
+	assert.StrContains(t, ft.errs[0], "run the tests with -easytpltest.update")
+
+	// render error
+	ft = &fakeT{TB: t}
+	easytpltest.Golden(ft, r, "not-exists", nil, easytpltest.WithLayout(""))
+	assert.Len(t, ft.errs, 1)
+	assert.StrContains(t, ft.errs[0], `render the template "not-exists" failed`)
+}
+
+func TestGolden_update(t *testing.T) {
+	r := newRenderer()
+	file := filepath.Join(t.TempDir(), "hello.golden")
+
+	assert.NoErr(t, flag.Set("update", "true"))
+	defer func() { *update = false }()
+	easytpltest.Golden(t, r, "hello", nil, easytpltest.WithLayout(""), easytpltest.WithFile(file))
+
+	*update = false
+	easytpltest.Golden(t, r, "hello", nil, easytpltest.WithLayout(""), easytpltest.WithFile(file))
+}
+
+func TestNormalize(t *testing.T) {
+	src := []byte("<ul>\n  <li>a  b</li>\n\n  <!-- comment --><li>c</li>\n</ul>")
+
+	assert.Eq(t, string(src), easytpltest.Normalize(src, easytpltest.Exact))
+	assert.Eq(t, "<ul>\n<li>a b</li>\n<!-- comment --><li>c</li>\n</ul>", easytpltest.Normalize(src, easytpltest.Whitespace))
+	assert.Eq(t, "<ul>\n<li>a b</li>\n<li>c</li>\n</ul>", easytpltest.Normalize(src, easytpltest.HTML))
+}
+
+func TestDiff(t *testing.T) {
+	want := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj"
+	got := "a\nb\nc\nD\ne\nf\ng\nh\ni\nj\nk"
+
+	assert.Eq(t, `--- want
++++ got
+  b
+  c
+- d
++ D
+  e
+  f
+...
+  i
+  j
++ k
+`, easytpltest.Diff(want, got))
+}
+
+func TestDiff_large(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+
+	want := sb.String()
+	got := strings.Replace(want, "line 2500\n", "LINE 2500\n", 1)
+	assert.Eq(t, `--- want
++++ got
+  line 2498
+  line 2499
+- line 2500
++ LINE 2500
+  line 2501
+  line 2502
+`, easytpltest.Diff(want, got))
+}
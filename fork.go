@@ -0,0 +1,137 @@
+package easytpl
+
+import (
+	"html/template"
+	"maps"
+	"slices"
+)
+
+// Fork create a new initialized renderer from the initialized renderer.
+//
+// The fork shares the parsed templates of the parent without re-reading files,
+// then it can add funcs, replace templates or change the default layout, will not affect the parent.
+//
+//   - the option funcs are applied before the fork is initialized, so can call AddFunc() in them.
+//   - the middlewares, composers and hooks are copied. the stats is not.
+//   - the fork can includeFrom the renderers in the registry of the parent, but it is not added to the registry.
+//   - the fork has a new fragment cache, unless set the FragmentCache by option func.
+//   - if the options changed the parsing of templates(eg: Sandbox, Delims, override the block funcs),
+//     the templates are parsed again from the loaded sources. will panic on parse error.
+//
+// NOTE: please don't load templates to the parent while forking, it is not safe for concurrent use.
+//
+// Usage:
+//
+//	tenant := r.Fork(easytpl.WithLayout("tenant/layout"), func(r *easytpl.Renderer) {
+//		r.AddFunc("brand", func() string { return "ACME" })
+//	})
+//	tenant.LoadString("home", "tenant home")
+func (r *Renderer) Fork(fns ...OptionFn) *Renderer {
+	r.requireInit("please call Init() before fork the renderer")
+
+	f := &Renderer{
+		Options:     r.Options,
+		bufPool:     newBufferPool(),
+		middlewares: slices.Clone(r.middlewares),
+		composers:   slices.Clone(r.composers),
+		tplDirs:     slices.Clone(r.tplDirs),
+		extMap:      maps.Clone(r.extMap),
+		registry:    r.registry,
+	}
+
+	// copy the loaded templates set. the master templates are never executed, so they can be cloned.
+	r.setMu.RLock()
+	f.root = template.Must(r.root.Clone())
+	f.fileMap = maps.Clone(r.fileMap)
+	f.layoutOf = maps.Clone(r.layoutOf)
+	f.baseTpl = maps.Clone(r.baseTpl)
+	f.sources = maps.Clone(r.sources)
+	f.srcNames = slices.Clone(r.srcNames)
+	f.versions = maps.Clone(r.versions)
+	if len(r.withExtends) > 0 {
+		f.withExtends = make(map[string]*template.Template, len(r.withExtends))
+		for name, tpl := range r.withExtends {
+			f.withExtends[name] = template.Must(tpl.Clone())
+		}
+	}
+	r.setMu.RUnlock()
+
+	// copy the options maps and slices, avoid affecting the parent.
+	f.FuncMap = maps.Clone(r.FuncMap)
+	f.ExtNames = slices.Clone(r.ExtNames)
+	f.LayoutParents = maps.Clone(r.LayoutParents)
+	f.ExtendsBase = maps.Clone(r.ExtendsBase)
+	f.SandboxIncludes = slices.Clone(r.SandboxIncludes)
+	f.FragmentCache = nil
+
+	r.globals.mu.RLock()
+	f.GlobalData = maps.Clone(r.GlobalData)
+	r.globals.mu.RUnlock()
+
+	if len(r.hooks) > 0 {
+		f.hooks = make(map[string][]HookFunc, len(r.hooks))
+		for name, fns := range r.hooks {
+			f.hooks[name] = slices.Clone(fns)
+		}
+	}
+
+	f.WithOptions(fns...)
+	if f.FragmentCache == nil {
+		f.FragmentCache = NewMemoryCache(DefaultCacheSize)
+	}
+	f.blockRe = blockRegex(f.Delims, blockFuncNames)
+
+	// bind the funcs to the fork
+	f.root.Funcs(f.includeFuncs())
+	if len(f.FuncMap) > 0 {
+		f.root.Funcs(f.FuncMap)
+	}
+	for _, tpl := range f.withExtends {
+		tpl.Funcs(f.includeFuncs())
+		if len(f.FuncMap) > 0 {
+			tpl.Funcs(f.FuncMap)
+		}
+	}
+
+	if f.EnableExtends {
+		f.waitBase = make(map[string][]byte)
+		if f.baseTpl == nil {
+			f.baseTpl = make(map[string]string)
+		}
+		if f.withExtends == nil {
+			f.withExtends = make(map[string]*template.Template)
+		}
+	}
+
+	f.init = true
+	if f.parseChanged(r) {
+		f.logDebug("easytpl: parse the templates again for the fork", "templates", len(f.srcNames))
+		f.reparseSources()
+	}
+
+	// rebuild the render func with the fork
+	f.Use()
+	f.logDebug("easytpl: fork the renderer", "templates", len(f.root.Templates()), "funcs", len(f.FuncMap))
+	return f
+}
+
+// parseChanged check the options changed the parsing of templates, compare with the parent.
+func (r *Renderer) parseChanged(p *Renderer) bool {
+	return r.Sandbox != p.Sandbox || r.Delims != p.Delims || !slices.Equal(r.blockFuncs(), p.blockFuncs())
+}
+
+// reparseSources parse all templates again from the loaded sources.
+func (r *Renderer) reparseSources() {
+	r.root = nil
+	r.ensureRoot()
+	if r.EnableExtends {
+		r.baseTpl = make(map[string]string)
+		r.waitBase = make(map[string][]byte)
+		r.withExtends = make(map[string]*template.Template)
+	}
+
+	for _, name := range slices.Clone(r.srcNames) {
+		r.loadBytes(name, r.sources[name], r.EnableExtends)
+	}
+	r.loadWaitBase()
+}
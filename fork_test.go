@@ -0,0 +1,135 @@
+package easytpl_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gookit/easytpl"
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+func TestRenderer_Fork(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewInited(easytpl.WithLayout("layout"))
+	r.LoadStrings(map[string]string{
+		"layout": `[{{ yield }}]`,
+		"home":   `home: {{ .name }}, {{ include "footer" }}`,
+		"footer": `footer`,
+	})
+
+	f := r.Fork(easytpl.WithLayout("tenant/layout"), func(r *easytpl.Renderer) {
+		r.AddFunc("brand", func() string { return "ACME" })
+	})
+	f.LoadStrings(map[string]string{
+		"tenant/layout": `<{{ brand }}>{{ yield }}`,
+		"footer":        `tenant footer`,
+	})
+
+	data := map[string]any{"name": "tom"}
+	is.NoErr(f.Render(bf, "home", data))
+	is.Eq("&lt;ACME>home: tom, tenant footer", bf.String())
+
+	// the parent is not affected
+	bf.Reset()
+	is.NoErr(r.Render(bf, "home", data))
+	is.Eq("[home: tom, footer]", bf.String())
+	is.Nil(r.Template("tenant/layout"))
+	is.Panics(func() {
+		r.AddFunc("brand", strings.ToUpper)
+	})
+
+	// fork the fork
+	f2 := f.Fork(easytpl.DisableLayout)
+	bf.Reset()
+	is.NoErr(f2.Render(bf, "home", data))
+	is.Eq("home: tom, tenant footer", bf.String())
+
+	is.Panics(func() {
+		easytpl.New().Fork()
+	})
+}
+
+func TestRenderer_Fork_extends(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewExtends(easytpl.DisableLayout)
+	r.LoadStrings(map[string]string{
+		"base": `{{ block "body" . }}default{{ end }}|{{ block "footer" . }}footer{{ end }}`,
+		"home": `{{ extends "base" }}
+{{ define "body" }}hi {{.}}{{ end }}`,
+	})
+
+	f := r.Fork(func(r *easytpl.Renderer) {
+		r.AddFunc("upper2", strings.ToUpper)
+	})
+	f.LoadStrings(map[string]string{
+		"about": `{{ extends "base" }}
+{{ define "footer" }}{{ upper2 . }}{{ end }}`,
+	})
+
+	is.NoErr(f.Execute(bf, "home", "tom"))
+	is.Eq("hi tom|footer", bf.String())
+
+	bf.Reset()
+	is.NoErr(f.Execute(bf, "about", "tom"))
+	is.Eq("default|TOM", bf.String())
+	is.Nil(r.Template("about"))
+
+	// replace the base on the fork
+	is.NoErr(f.Replace("base", `{{ block "body" . }}default{{ end }}+{{ block "footer" . }}footer{{ end }}`))
+	bf.Reset()
+	is.NoErr(f.Execute(bf, "home", "tom"))
+	is.Eq("hi tom+footer", bf.String())
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "home", "tom"))
+	is.Eq("hi tom|footer", bf.String())
+}
+
+func TestRenderer_Fork_reparse(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewInited()
+	r.LoadStrings(map[string]string{
+		"home": `home {{ . }}`,
+		"page": `{{ push "x" }}page{{ end }}{{ stack "x" }}`,
+	})
+
+	// the templates are parsed again on sandbox mode
+	f := r.Fork(easytpl.WithSandbox())
+	is.NoErr(f.Execute(bf, "home", "tom"))
+	is.Eq("home tom", bf.String())
+
+	r.LoadString("env", `{{ env "HOME" }}`)
+	is.PanicsErrMsg(func() {
+		r.Fork(easytpl.WithSandbox())
+	}, `template: env:1: function "env" not defined`)
+
+	// override the block funcs, the push block is not rewritten
+	is.NoErr(r.Remove("env"))
+	is.PanicsErrMsg(func() {
+		r.Fork(func(r *easytpl.Renderer) {
+			r.AddFunc("push", func(s string) string { return s })
+		})
+	}, `template: page:1: unexpected {{end}}`)
+}
+
+func TestRenderer_Fork_registry(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	site := easytpl.New()
+	app := easytpl.New()
+	easytpl.NewRegistry().Add("site", site).Add("app", app).MustInit()
+
+	site.LoadString("footer", `footer {{ . }}`)
+	app.LoadString("page", `page, {{ includeFrom "site" "footer" . }}`)
+
+	f := app.Fork()
+	is.NoErr(f.Execute(bf, "page", "tom"))
+	is.Eq("page, footer tom", bf.String())
+}
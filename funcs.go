@@ -0,0 +1,331 @@
+package easytpl
+
+import (
+	"html"
+	"html/template"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gookit/goutil/strutil"
+)
+
+/*************************************************************
+ * built-in html helper funcs
+ *************************************************************/
+
+var nl2brRpl = strings.NewReplacer("\r\n", "<br>\n", "\n", "<br>\n", "\r", "<br>\n")
+
+// nl2br escape the string, then insert "<br>" before all newlines.
+func nl2br(s string) template.HTML {
+	return template.HTML(nl2brRpl.Replace(template.HTMLEscapeString(s)))
+}
+
+// stripTags remove all HTML tags and comments from the string.
+func stripTags(s string) string {
+	if strings.IndexByte(s, '<') < 0 {
+		return s
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); {
+		if s[i] != '<' {
+			sb.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		end := tagEnd(s, i)
+		if end < 0 { // not a tag
+			sb.WriteByte(s[i])
+			i++
+			continue
+		}
+		i = end
+	}
+	return sb.String()
+}
+
+// tagEnd find the end position(after '>') of the tag or comment starts at pos.
+// returns -1 if s[pos:] is not start with a tag.
+func tagEnd(s string, pos int) int {
+	rest := s[pos:]
+	if strings.HasPrefix(rest, "<!--") {
+		if i := strings.Index(rest[4:], "-->"); i >= 0 {
+			return pos + 4 + i + 3
+		}
+		return len(s)
+	}
+
+	if len(rest) < 2 {
+		return -1
+	}
+	if c := rest[1]; c != '/' && c != '!' && !isASCIILetter(c) {
+		return -1
+	}
+
+	var quote byte
+	for i := 1; i < len(rest); i++ {
+		switch c := rest[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			return pos + i + 1
+		}
+	}
+	return len(s)
+}
+
+// html void elements, they have no end tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// truncateHTML truncate the HTML string to the max visible chars length,
+// and close all opened tags.
+//
+// Usage:
+//
+//	{{ truncateHTML .Body 100 }}
+//	{{ truncateHTML .Body 100 " [more]" }}
+func truncateHTML(s string, length int, suffix ...string) template.HTML {
+	end := "..."
+	if len(suffix) > 0 {
+		end = suffix[0]
+	}
+
+	var (
+		sb    strings.Builder
+		count int
+		open  []string
+	)
+
+	for i := 0; i < len(s); {
+		if count >= length {
+			// only append suffix on has more visible text
+			if strings.TrimSpace(stripTags(s[i:])) != "" {
+				sb.WriteString(end)
+			}
+			break
+		}
+
+		c := s[i]
+		if c == '<' {
+			if j := tagEnd(s, i); j > 0 {
+				tag := s[i:j]
+				sb.WriteString(tag)
+				i = j
+
+				name, closing, selfClose := parseTagName(tag)
+				if name == "" || selfClose || voidElements[name] {
+					continue
+				}
+
+				if !closing {
+					open = append(open, name)
+				} else if k := lastIndexOf(open, name); k >= 0 {
+					open = open[:k]
+				}
+				continue
+			}
+		}
+
+		// an entity as one char. eg: "&amp;"
+		if c == '&' {
+			if j := strings.IndexByte(s[i:], ';'); j > 0 && j < 10 {
+				sb.WriteString(s[i : i+j+1])
+				i += j + 1
+				count++
+				continue
+			}
+		}
+
+		_, size := utf8.DecodeRuneInString(s[i:])
+		sb.WriteString(s[i : i+size])
+		i += size
+		count++
+	}
+
+	// close opened tags
+	for k := len(open) - 1; k >= 0; k-- {
+		sb.WriteString("</" + open[k] + ">")
+	}
+	return template.HTML(sb.String())
+}
+
+// parseTagName parse tag name from tag string. eg: "<div class='a'>" -> "div"
+func parseTagName(tag string) (name string, closing, selfClose bool) {
+	if strings.HasPrefix(tag, "<!") {
+		return "", false, false
+	}
+
+	tag = strings.TrimSuffix(strings.TrimPrefix(tag, "<"), ">")
+	if strings.HasPrefix(tag, "/") {
+		closing = true
+		tag = tag[1:]
+	}
+	if strings.HasSuffix(tag, "/") {
+		selfClose = true
+	}
+
+	end := strings.IndexAny(tag, " \t\r\n/")
+	if end < 0 {
+		end = len(tag)
+	}
+	return strings.ToLower(tag[:end]), closing, selfClose
+}
+
+// classNames build CSS class names string from strings and maps.
+// map key will be added on the value is true.
+//
+// Usage:
+//
+//	<div class="{{ classNames "btn" .Classes }}">
+//	// Classes: {"active": true, "disabled": false}
+//	// output: <div class="btn active">
+func classNames(args ...any) string {
+	var names []string
+	for _, arg := range args {
+		if arg == nil {
+			continue
+		}
+
+		switch typVal := arg.(type) {
+		case string:
+			if typVal != "" {
+				names = append(names, typVal)
+			}
+		case []string:
+			names = append(names, typVal...)
+		default:
+			rv := reflect.ValueOf(arg)
+			if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+				continue
+			}
+
+			var keys []string
+			for _, key := range rv.MapKeys() {
+				if ok, _ := template.IsTrue(rv.MapIndex(key).Interface()); ok {
+					keys = append(keys, key.String())
+				}
+			}
+			sort.Strings(keys)
+			names = append(names, keys...)
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+// attrs render escaped HTML attribute list from a map.
+//
+//   - value is true: render attribute name only. eg: disabled
+//   - value is false or nil: skip the attribute
+//   - skip the invalid names, event handlers "on*" and "style", they need the context-aware escaping.
+//   - the unsafe URL value of URL attributes is replaced to "#ZgotmplZ", same as html/template.
+//     allowed schemes: http, https, mailto. use template.URL for trust the value.
+//
+// Usage:
+//
+//	<input {{ attrs .Attrs }}>
+//	// attrs: {"type": "text", "disabled": true}
+//	// output: <input disabled type="text">
+func attrs(mp map[string]any) template.HTMLAttr {
+	keys := make([]string, 0, len(mp))
+	for key := range mp {
+		if isAttrName(key) && !isUnsafeAttr(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, key := range keys {
+		switch val := mp[key].(type) {
+		case nil:
+			continue
+		case bool:
+			if val {
+				sb.WriteString(" " + key)
+			}
+		case template.URL:
+			sb.WriteString(" " + key + `="`)
+			sb.WriteString(html.EscapeString(string(val)))
+			sb.WriteByte('"')
+		default:
+			s := strutil.SafeString(val)
+			if urlAttrs[strings.ToLower(key)] && !isSafeURL(s) {
+				s = unsafeURL
+			}
+
+			sb.WriteString(" " + key + `="`)
+			sb.WriteString(html.EscapeString(s))
+			sb.WriteByte('"')
+		}
+	}
+	return template.HTMLAttr(strings.TrimLeft(sb.String(), " "))
+}
+
+// unsafeURL the replacement of the unsafe URL, same as html/template
+const unsafeURL = "#ZgotmplZ"
+
+// urlAttrs the attributes with URL value
+var urlAttrs = map[string]bool{
+	"action": true, "background": true, "cite": true, "codebase": true, "data": true,
+	"formaction": true, "href": true, "icon": true, "longdesc": true, "manifest": true,
+	"poster": true, "src": true, "usemap": true, "xlink:href": true,
+}
+
+// isUnsafeAttr check the attribute need the context-aware escaping. eg: onclick, style
+func isUnsafeAttr(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasPrefix(name, "on") || name == "style" || name == "srcdoc"
+}
+
+// isSafeURL check the URL is relative or the scheme is http, https or mailto.
+func isSafeURL(s string) bool {
+	s = strings.TrimSpace(s)
+	i := strings.IndexAny(s, ":/?#")
+	if i < 0 || s[i] != ':' {
+		return true
+	}
+
+	switch strings.ToLower(s[:i]) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
+
+func isAttrName(s string) bool {
+	if s == "" || !isASCIILetter(s[0]) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !isASCIILetter(c) && !(c >= '0' && c <= '9') && c != '-' && c != '_' && c != ':' {
+			return false
+		}
+	}
+	return true
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func lastIndexOf(ss []string, s string) int {
+	for i := len(ss) - 1; i >= 0; i-- {
+		if ss[i] == s {
+			return i
+		}
+	}
+	return -1
+}
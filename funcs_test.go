@@ -0,0 +1,98 @@
+package easytpl_test
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+
+	"github.com/gookit/easytpl"
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+func TestBuiltInFuncs_safeTypes(t *testing.T) {
+	is := assert.New(t)
+	r := easytpl.NewInited()
+	bf := new(bytes.Buffer)
+
+	err := r.String(bf, `<a href="{{ safeURL . }}">`, "javascript:go")
+	is.NoErr(err)
+	is.Eq(`<a href="javascript:go">`, bf.String())
+
+	bf.Reset()
+	err = r.String(bf, `<a href="{{ . }}">`, "javascript:go")
+	is.NoErr(err)
+	is.Eq(`<a href="#ZgotmplZ">`, bf.String())
+
+	bf.Reset()
+	err = r.String(bf, `<script>var a = {{ safeJS . }};</script>`, "a + b")
+	is.NoErr(err)
+	is.Eq(`<script>var a = a + b;</script>`, bf.String())
+
+	bf.Reset()
+	err = r.String(bf, `<p style="{{ safeCSS . }}">`, "color: red")
+	is.NoErr(err)
+	is.Eq(`<p style="color: red">`, bf.String())
+
+	bf.Reset()
+	err = r.String(bf, `<p {{ safeAttr . }}>`, `dir="ltr"`)
+	is.NoErr(err)
+	is.Eq(`<p dir="ltr">`, bf.String())
+}
+
+func TestBuiltInFuncs_html(t *testing.T) {
+	is := assert.New(t)
+	r := easytpl.NewInited()
+	bf := new(bytes.Buffer)
+
+	err := r.String(bf, `{{ nl2br . }}`, "a<b>\nc")
+	is.NoErr(err)
+	is.Eq("a&lt;b&gt;<br>\nc", bf.String())
+
+	bf.Reset()
+	err = r.String(bf, `{{ stripTags . }}`, `<p class="a">hello <b>tom</b></p><!-- note -->`)
+	is.NoErr(err)
+	is.Eq("hello tom", bf.String())
+
+	bf.Reset()
+	err = r.String(bf, `{{ truncateHTML . 8 }}`, `<p>hello <b>tom &amp; john</b></p>`)
+	is.NoErr(err)
+	is.Eq("<p>hello <b>to...</b></p>", bf.String())
+
+	bf.Reset()
+	err = r.String(bf, `{{ truncateHTML . 20 "" }}`, `<p>hello<br>tom</p>`)
+	is.NoErr(err)
+	is.Eq("<p>hello<br>tom</p>", bf.String())
+
+	bf.Reset()
+	err = r.String(bf, `<p class="{{ classNames "btn" .cls }}">`, easytpl.M{
+		"cls": map[string]bool{"active": true, "disabled": false, "big": true},
+	})
+	is.NoErr(err)
+	is.Eq(`<p class="btn active big">`, bf.String())
+
+	bf.Reset()
+	err = r.String(bf, `<input {{ attrs . }}>`, easytpl.M{
+		"type":     "text",
+		"value":    `a"b<c>`,
+		"disabled": true,
+		"readonly": false,
+		"bad name": "skip",
+		"-bad":     "skip",
+		"onclick":  "alert(1)",
+		"OnFocus":  "alert(1)",
+		"style":    "color: red",
+	})
+	is.NoErr(err)
+	is.Eq(`<input disabled type="text" value="a&#34;b&lt;c&gt;">`, bf.String())
+
+	bf.Reset()
+	err = r.String(bf, `<a {{ attrs . }}>`, easytpl.M{
+		"href":   " JavaScript:alert(1)",
+		"src":    "/img/a.png?x=1",
+		"action": "https://example.com/a",
+		"cite":   template.URL("javascript:void(0)"),
+		"title":  "javascript:alert(1)",
+	})
+	is.NoErr(err)
+	is.Eq(`<a action="https://example.com/a" cite="javascript:void(0)" href="#ZgotmplZ" src="/img/a.png?x=1" title="javascript:alert(1)">`, bf.String())
+}
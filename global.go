@@ -0,0 +1,67 @@
+package easytpl
+
+import "sync"
+
+// GlobalKey the key of the global data on merge to render data. eg: {{ $.Global.siteName }}
+const GlobalKey = "Global"
+
+// globalData the lock for the Options.GlobalData
+type globalData struct {
+	mu sync.RWMutex
+}
+
+// SetGlobal set a global data, it is accessible in every template, layout and include.
+func (r *Renderer) SetGlobal(key string, val any) {
+	r.globals.mu.Lock()
+	defer r.globals.mu.Unlock()
+
+	if r.GlobalData == nil {
+		r.GlobalData = make(map[string]any)
+	}
+	r.GlobalData[key] = val
+}
+
+// Global get a global data value by key.
+func (r *Renderer) Global(key string) any {
+	r.globals.mu.RLock()
+	defer r.globals.mu.RUnlock()
+	return r.GlobalData[key]
+}
+
+// withGlobal merge the global data to the render data, when the data is an M or map[string]any.
+//
+// the global data will be set on the key GlobalKey, if the data already has the key, will not override it.
+func (r *Renderer) withGlobal(v any) any {
+	var data map[string]any
+	switch typ := v.(type) {
+	case M:
+		data = typ
+	case map[string]any:
+		data = typ
+	default:
+		return v
+	}
+
+	if _, ok := data[GlobalKey]; ok {
+		return v
+	}
+
+	r.globals.mu.RLock()
+	defer r.globals.mu.RUnlock()
+	if len(r.GlobalData) == 0 {
+		return v
+	}
+
+	// copy for not modify the user data
+	newData := make(map[string]any, len(data)+1)
+	for key, val := range data {
+		newData[key] = val
+	}
+
+	global := make(map[string]any, len(r.GlobalData))
+	for key, val := range r.GlobalData {
+		global[key] = val
+	}
+	newData[GlobalKey] = global
+	return newData
+}
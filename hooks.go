@@ -0,0 +1,190 @@
+package easytpl
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// built-in hook names
+const (
+	HookBeforeRender  = "beforeRender"
+	HookAfterRender   = "afterRender"
+	HookBeforeInclude = "beforeInclude"
+	HookAfterInclude  = "afterInclude"
+	// HookOnError will fire after the "after" hook on render or include failed.
+	HookOnError = "onError"
+)
+
+var hookNames = []string{HookBeforeRender, HookAfterRender, HookBeforeInclude, HookAfterInclude, HookOnError}
+
+// HookFunc the render hook func
+type HookFunc func(e *RenderEvent)
+
+// RenderEvent the event data for render hooks.
+//
+// NOTE: the Duration, Bytes and Err are only set on the "after" and error hooks.
+type RenderEvent struct {
+	// Hook current hook name. eg: HookBeforeRender
+	Hook string
+	// Ctx the context of the render call
+	Ctx context.Context
+	// Name the template name
+	Name string
+	// File the source file path of the template. empty on loaded from string.
+	File string
+	// Layout the layout template name. only for render.
+	Layout string
+	// Include is true on include other template.
+	Include bool
+
+	// Duration of the render or include
+	Duration time.Duration
+	// Bytes written size
+	Bytes int
+	// Err the render error
+	Err error
+}
+
+// On add a hook func for the render events. see HookBeforeRender, HookAfterRender ...
+//
+// NOTE: please add hooks before rendering, it is not safe for concurrent use.
+//
+// Usage:
+//
+//	r.On(easytpl.HookAfterRender, func(e *easytpl.RenderEvent) {
+//		renderDuration.WithLabelValues(e.Name).Observe(e.Duration.Seconds())
+//	})
+func (r *Renderer) On(hook string, fn HookFunc) {
+	if !isHookName(hook) {
+		panicf("the hook name %q is invalid, allowed: %v", hook, hookNames)
+	}
+
+	if r.hooks == nil {
+		r.hooks = make(map[string][]HookFunc)
+	}
+	r.hooks[hook] = append(r.hooks[hook], fn)
+}
+
+func isHookName(hook string) bool {
+	for _, name := range hookNames {
+		if name == hook {
+			return true
+		}
+	}
+	return false
+}
+
+// fire the hook funcs
+func (r *Renderer) fire(hook string, e *RenderEvent) {
+	for _, fn := range r.hooks[hook] {
+		e.Hook = hook
+		fn(e)
+	}
+}
+
+// track run the render or include, fire the hooks and collect the stats.
+func (r *Renderer) track(e *RenderEvent, run func() (int, error)) error {
+	before, after := HookBeforeRender, HookAfterRender
+	if e.Include {
+		before, after = HookBeforeInclude, HookAfterInclude
+	}
+
+	e.File = r.fileOf(e.Name)
+	r.fire(before, e)
+
+	start := time.Now()
+	e.Bytes, e.Err = run()
+	e.Duration = time.Since(start)
+
+	r.stats.add(r.cleanExt(e.Name), e)
+	r.fire(after, e)
+	if e.Err != nil {
+		r.fire(HookOnError, e)
+		// include errors will be returned to the render, so only log on render.
+		if r.Logger != nil && !e.Include {
+			r.Logger.ErrorContext(e.Ctx, "easytpl: render template failed", "name", e.Name, "layout", e.Layout, "error", e.Err)
+		}
+	}
+	return e.Err
+}
+
+// countWriter count the written bytes
+type countWriter struct {
+	w io.Writer
+	n int
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += n
+	return n, err
+}
+
+/*************************************************************
+ * render stats
+ *************************************************************/
+
+// TemplateStats the render stats of a template. includes render and include calls.
+type TemplateStats struct {
+	// Calls number of the render and include calls
+	Calls int64
+	// Errors number of the failed calls
+	Errors int64
+	// Total duration of all calls
+	Total time.Duration
+}
+
+// Avg get the average duration of calls
+func (s TemplateStats) Avg() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Calls)
+}
+
+type statsCollector struct {
+	mu   sync.Mutex
+	data map[string]*TemplateStats
+}
+
+func (sc *statsCollector) add(name string, e *RenderEvent) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if sc.data == nil {
+		sc.data = make(map[string]*TemplateStats)
+	}
+
+	s, ok := sc.data[name]
+	if !ok {
+		s = &TemplateStats{}
+		sc.data[name] = s
+	}
+
+	s.Calls++
+	s.Total += e.Duration
+	if e.Err != nil {
+		s.Errors++
+	}
+}
+
+// Stats get the render stats of all rendered templates. key is template name.
+func (r *Renderer) Stats() map[string]TemplateStats {
+	r.stats.mu.Lock()
+	defer r.stats.mu.Unlock()
+
+	mp := make(map[string]TemplateStats, len(r.stats.data))
+	for name, s := range r.stats.data {
+		mp[name] = *s
+	}
+	return mp
+}
+
+// ResetStats clear the collected render stats
+func (r *Renderer) ResetStats() {
+	r.stats.mu.Lock()
+	r.stats.data = nil
+	r.stats.mu.Unlock()
+}
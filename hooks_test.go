@@ -0,0 +1,66 @@
+package easytpl_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gookit/easytpl"
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+func TestRenderer_On(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewInited()
+	r.LoadStrings(map[string]string{
+		"layout": `[{{ yield }}]`,
+		"header": `header`,
+		"home":   `{{ include "header" }}, home {{ . }}`,
+		"bad":    `{{ .Name.Sub }}`,
+	})
+
+	var events []string
+	for _, hook := range []string{
+		easytpl.HookBeforeRender,
+		easytpl.HookAfterRender,
+		easytpl.HookBeforeInclude,
+		easytpl.HookAfterInclude,
+		easytpl.HookOnError,
+	} {
+		r.On(hook, func(e *easytpl.RenderEvent) {
+			events = append(events, e.Hook+":"+e.Name+":"+e.Layout)
+			if e.Hook == easytpl.HookAfterRender && e.Err == nil {
+				is.Eq(bf.Len(), e.Bytes)
+				is.True(e.Duration > 0)
+			}
+		})
+	}
+
+	is.NoErr(r.Render(bf, "home", "tom", "layout"))
+	is.Eq("[header, home tom]", bf.String())
+	is.Eq([]string{
+		"beforeRender:home:layout",
+		"beforeInclude:header:",
+		"afterInclude:header:",
+		"afterRender:home:layout",
+	}, events)
+
+	events = events[:0]
+	is.Err(r.Execute(bf, "bad", map[string]string{"Name": "tom"}))
+	is.Eq([]string{"beforeRender:bad:", "afterRender:bad:", "onError:bad:"}, events)
+
+	stats := r.Stats()
+	is.Eq(int64(1), stats["home"].Calls)
+	is.Eq(int64(0), stats["home"].Errors)
+	is.Eq(int64(1), stats["header"].Calls)
+	is.Eq(int64(1), stats["bad"].Errors)
+	is.True(stats["home"].Avg() >= stats["header"].Avg())
+
+	r.ResetStats()
+	is.Empty(r.Stats())
+
+	is.Panics(func() {
+		r.On("invalid", func(e *easytpl.RenderEvent) {})
+	})
+}
@@ -0,0 +1,129 @@
+package easytpl
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+
+	"github.com/gookit/goutil/errorx"
+)
+
+var layoutBytes = []byte("layout ")
+
+// parse line '{{ layout "site/layout" }}' and get "site/layout".
+// for '{{ layout none }}' will return empty string.
+func getLayoutTplName(line []byte, td TplDelims) (string, bool) {
+	line = bytes.TrimSpace(line)
+	left, right := []byte(td.Left), []byte(td.Right)
+	if len(line) < len(left)+len(right) || !bytes.HasPrefix(line, left) || !bytes.HasSuffix(line, right) {
+		return "", false
+	}
+
+	// remove delimiters, spaces and trim markers. eg: {{- layout "name" -}}
+	content := bytes.TrimSpace(line[len(left) : len(line)-len(right)])
+	content = bytes.TrimSpace(bytes.TrimSuffix(bytes.TrimPrefix(content, []byte("- ")), []byte(" -")))
+	if !bytes.HasPrefix(content, layoutBytes) {
+		return "", false
+	}
+
+	arg := bytes.TrimSpace(content[len(layoutBytes):])
+	if string(arg) == "none" {
+		return "", true
+	}
+	if n := len(arg); n >= 2 && arg[0] == arg[n-1] && (arg[0] == '"' || arg[0] == '`') {
+		return string(arg[1 : n-1]), true
+	}
+	return "", false
+}
+
+// parseLayoutDirective parse and remove the layout directive on the first line of the template.
+// returns the rest text and whether the directive is found.
+func (r *Renderer) parseLayoutDirective(tplName string, bs []byte) ([]byte, bool) {
+	text := bytes.TrimLeft(bs, "\r\n\t ")
+	line, rest := text, []byte(nil)
+	if i := bytes.IndexByte(text, '\n'); i >= 0 {
+		line, rest = text[:i], text[i+1:]
+	}
+
+	layout, ok := getLayoutTplName(line, r.Delims)
+	if !ok {
+		return bs, false
+	}
+
+	r.logDebug("easytpl: parse the layout directive", "name", tplName, "layout", layout)
+	if r.layoutOf == nil {
+		r.layoutOf = make(map[string]string)
+	}
+	r.layoutOf[r.cleanExt(tplName)] = layout
+	return rest, true
+}
+
+// parentLayout get the parent layout name of the layout, from Options.LayoutParents or the layout directive.
+func (r *Renderer) parentLayout(layout string) string {
+	name := r.cleanExt(layout)
+	for _, key := range []string{name, layout} {
+		if parent, ok := r.LayoutParents[key]; ok {
+			return parent
+		}
+	}
+	layout, _ = r.layoutDirective(name)
+	return layout
+}
+
+// layoutUsers find the users of the template as layout, from Options.Layout, Options.LayoutParents and the layout directives.
+//
+// NOTE: should be called with the setMu locked.
+func (r *Renderer) layoutUsers(tplName string) []string {
+	var users []string
+	name := r.cleanExt(tplName)
+	if !r.DisableLayout && r.Layout != "" && r.cleanExt(r.Layout) == name {
+		users = append(users, "Options.Layout")
+	}
+	for child, parent := range r.LayoutParents {
+		if r.cleanExt(parent) == name {
+			users = append(users, child)
+		}
+	}
+	for page, layout := range r.layoutOf {
+		if layout != "" && r.cleanExt(layout) == name {
+			users = append(users, page)
+		}
+	}
+
+	sort.Strings(users)
+	return users
+}
+
+// layoutDirective get the layout declared by directive on the template. the "none" is empty string.
+func (r *Renderer) layoutDirective(tplName string) (string, bool) {
+	r.setMu.RLock()
+	defer r.setMu.RUnlock()
+
+	layout, ok := r.layoutOf[r.cleanExt(tplName)]
+	return layout, ok
+}
+
+// layoutChain resolve the layout and all parent layouts, returns names from inner to outer.
+//
+// eg: ["admin/layout", "site/layout"]
+func (r *Renderer) layoutChain(layout string) ([]string, error) {
+	chain := []string{layout}
+	for name := layout; ; {
+		parent := r.parentLayout(name)
+		if parent == "" {
+			return chain, nil
+		}
+
+		for _, n := range chain {
+			if r.cleanExt(n) == r.cleanExt(parent) {
+				return nil, errorx.Ef("easytpl: layout cycle detected: %s -> %s", strings.Join(chain, " -> "), parent)
+			}
+		}
+		if r.master(parent) == nil {
+			return nil, errorx.Ef("easytpl: the parent layout %q of %q is not found", parent, name)
+		}
+
+		chain = append(chain, parent)
+		name = parent
+	}
+}
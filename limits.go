@@ -0,0 +1,131 @@
+package easytpl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/template/parse"
+	"time"
+)
+
+// DefaultMaxDepth default max include/yield nesting depth, use on Limits.MaxDepth is 0.
+const DefaultMaxDepth = 100
+
+// Limits for execute the user-authored templates. zero value means no limit.
+type Limits struct {
+	// Timeout max duration of each render. the deadline of the render context is also honoured.
+	Timeout time.Duration
+	// MaxOutputBytes max output bytes of each render.
+	MaxOutputBytes int
+	// MaxDepth max include/yield nesting depth. if is 0, will use DefaultMaxDepth
+	MaxDepth int
+	// MaxRangeIterations max total range loop iterations of each render.
+	MaxRangeIterations int
+}
+
+// LimitError is returned on the render exceeds one of the Limits.
+//
+// Usage:
+//
+//	if errors.Is(err, easytpl.ErrMaxDepth) {
+//		// ...
+//	}
+type LimitError struct {
+	// Limit name. eg: "MaxDepth"
+	Limit string
+	// Max the limit value
+	Max int
+}
+
+// Error string
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("easytpl: the render exceeds the limit %s=%d", e.Limit, e.Max)
+}
+
+// Is check the target is the same kind limit error.
+func (e *LimitError) Is(target error) bool {
+	t, ok := target.(*LimitError)
+	return ok && t.Limit == e.Limit
+}
+
+// all limit errors, can be used for errors.Is()
+var (
+	ErrMaxOutputBytes     = &LimitError{Limit: "MaxOutputBytes"}
+	ErrMaxDepth           = &LimitError{Limit: "MaxDepth"}
+	ErrMaxRangeIterations = &LimitError{Limit: "MaxRangeIterations"}
+)
+
+// WithLimits set the default execute limits for each render.
+func WithLimits(limits Limits) OptionFn {
+	return func(r *Renderer) { r.Limits = limits }
+}
+
+type limitsCtxKey struct{}
+
+// ContextWithLimits returns a new context with the limits, it will override the Options.Limits on render.
+//
+// Usage:
+//
+//	ctx := easytpl.ContextWithLimits(ctx, easytpl.Limits{MaxOutputBytes: 1024})
+//	err := r.RenderContext(ctx, w, "user/page", data)
+func ContextWithLimits(ctx context.Context, limits Limits) context.Context {
+	return context.WithValue(ctx, limitsCtxKey{}, limits)
+}
+
+// limitsFromContext get limits from the context, returns def if not set.
+func limitsFromContext(ctx context.Context, def Limits) Limits {
+	if limits, ok := ctx.Value(limitsCtxKey{}).(Limits); ok {
+		return limits
+	}
+	return def
+}
+
+/*************************************************************
+ * limit helpers
+ *************************************************************/
+
+// limitWriter check the render context and output size on each write.
+type limitWriter struct {
+	w  io.Writer
+	st *renderState
+	n  int
+	// captures of the push/prepend blocks, will write to the last one if not empty.
+	captures []*capture
+}
+
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	if err := lw.st.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	lw.n += len(p)
+	if max := lw.st.limits.MaxOutputBytes; max > 0 && lw.n > max {
+		return 0, &LimitError{Limit: "MaxOutputBytes", Max: max}
+	}
+
+	if n := len(lw.captures); n > 0 {
+		return lw.captures[n-1].buf.Write(p)
+	}
+	return lw.w.Write(p)
+}
+
+// tickFuncName the internal func for check limits on each range iteration.
+const tickFuncName = "_easytpl_range_tick"
+
+// addRangeTicks insert a tick call to the beginning of each range body. like:
+//
+//	{{ range .List }}{{ if _easytpl_range_tick }}{{ end }} ... {{ end }}
+//
+// NOTE: must call it before the template is executed(escaped).
+func addRangeTicks(tree *parse.Tree) {
+	walkTree(tree.Root, func(n parse.Node) bool {
+		rn, ok := n.(*parse.RangeNode)
+		if !ok || rn.List == nil {
+			return true
+		}
+
+		tick := newCallIf(tree, rn.List.Position(), rn.Line, tickFuncName)
+		rn.List.Nodes = append([]parse.Node{tick}, rn.List.Nodes...)
+		return true
+	})
+}
@@ -0,0 +1,109 @@
+package easytpl_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gookit/easytpl"
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+func TestRenderer_Limits(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewInited(easytpl.WithLimits(easytpl.Limits{
+		MaxDepth:           3,
+		MaxOutputBytes:     20,
+		MaxRangeIterations: 5,
+	}))
+	r.LoadStrings(map[string]string{
+		"loop":  `{{ include "loop" }}`,
+		"big":   `{{ range . }}0123456789{{ end }}`,
+		"items": `{{ range . }}{{ . }}{{ end }}`,
+	})
+
+	err := r.Execute(bf, "loop", nil)
+	is.True(errors.Is(err, easytpl.ErrMaxDepth))
+	var le *easytpl.LimitError
+	is.True(errors.As(err, &le))
+	is.Eq(3, le.Max)
+
+	bf.Reset()
+	err = r.Execute(bf, "big", []int{1, 2, 3})
+	is.True(errors.Is(err, easytpl.ErrMaxOutputBytes))
+	is.Empty(bf.String())
+
+	bf.Reset()
+	err = r.Execute(bf, "items", []int{1, 2, 3, 4, 5})
+	is.NoErr(err)
+	is.Eq("12345", bf.String())
+
+	bf.Reset()
+	err = r.Execute(bf, "items", []int{1, 2, 3, 4, 5, 6})
+	is.True(errors.Is(err, easytpl.ErrMaxRangeIterations))
+	is.False(errors.Is(err, easytpl.ErrMaxDepth))
+
+	// override on the render call
+	bf.Reset()
+	ctx := easytpl.ContextWithLimits(context.Background(), easytpl.Limits{MaxRangeIterations: 10})
+	err = r.ExecuteContext(ctx, bf, "items", []int{1, 2, 3, 4, 5, 6})
+	is.NoErr(err)
+	is.Eq("123456", bf.String())
+}
+
+func TestRenderer_RenderContext(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewInited(func(r *easytpl.Renderer) {
+		r.AddFunc("sleep", func() string {
+			time.Sleep(5 * time.Millisecond)
+			return ""
+		})
+	})
+	r.LoadString("slow", `{{ range . }}{{ sleep }}{{ . }}{{ end }}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := r.RenderContext(ctx, bf, "slow", []int{1, 2}, "")
+	is.True(errors.Is(err, context.Canceled))
+
+	bf.Reset()
+	ctx = easytpl.ContextWithLimits(context.Background(), easytpl.Limits{Timeout: 8 * time.Millisecond})
+	err = r.RenderContext(ctx, bf, "slow", []int{1, 2, 3, 4, 5}, "")
+	is.True(errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestRenderer_Render_concurrent(t *testing.T) {
+	r := easytpl.NewInited(easytpl.WithLayout("layout"))
+	r.LoadStrings(map[string]string{
+		"layout": `[{{ yield }}]`,
+		"home":   `{{ current_tpl }}:{{ . }}`,
+		"other":  `{{ current_tpl }}:{{ include "home" . }}`,
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bf := new(bytes.Buffer)
+			name := "home"
+			want := fmt.Sprintf("[home:%d]", i)
+			if i%2 == 1 {
+				name = "other"
+				want = fmt.Sprintf("[other:home:%d]", i)
+			}
+
+			assert.NoErr(t, r.Render(bf, name, i))
+			assert.Eq(t, want, bf.String())
+		}(i)
+	}
+	wg.Wait()
+}
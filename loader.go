@@ -0,0 +1,459 @@
+package easytpl
+
+import (
+	"errors"
+	"html/template"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gookit/goutil/errorx"
+	"github.com/gookit/goutil/maputil"
+)
+
+// Loader load the template contents from a storage. eg: files, database
+//
+// The errors for the template not exists should wrap the fs.ErrNotExist.
+type Loader interface {
+	// Names list all template names. eg: ["home", "admin/layout"]
+	Names() ([]string, error)
+	// Read the template contents by name.
+	Read(name string) ([]byte, error)
+	// Version get the modification version of the template. eg: modify time, revision, hash.
+	// the template will be reloaded on the version changed. see Renderer.ReloadChanged()
+	Version(name string) (string, error)
+}
+
+// errNotFound build the template not found error, it wraps the fs.ErrNotExist.
+func errNotFound(name string) error {
+	return errorx.Ef("easytpl: the template %q is not found: %w", name, fs.ErrNotExist)
+}
+
+/*************************************************************
+ * file loader
+ *************************************************************/
+
+// FileLoader load the templates from a directory.
+type FileLoader struct {
+	// Dir the templates directory
+	Dir string
+	// ExtNames supported template extensions. eg {"tpl", "html"}
+	ExtNames []string
+}
+
+// NewFileLoader create a new file loader. default extensions is "tpl", "html"
+func NewFileLoader(dir string, extNames ...string) *FileLoader {
+	if len(extNames) == 0 {
+		extNames = []string{"tpl", "html"}
+	}
+	return &FileLoader{Dir: dir, ExtNames: extNames}
+}
+
+// Names list all template names in the directory. name is the relative path without extension.
+func (l *FileLoader) Names() ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(l.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		ext := filepath.Ext(path)
+		if !l.isValidExt(ext) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(l.Dir, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel[:len(rel)-len(ext)]))
+		return nil
+	})
+	return names, err
+}
+
+// Read the template file contents by name
+func (l *FileLoader) Read(name string) ([]byte, error) {
+	path := l.FilePath(name)
+	if path == "" {
+		return nil, errNotFound(name)
+	}
+	return os.ReadFile(path)
+}
+
+// Version get the modify time and size of the template file.
+func (l *FileLoader) Version(name string) (string, error) {
+	path := l.FilePath(name)
+	if path == "" {
+		return "", errNotFound(name)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(fi.ModTime().UnixNano(), 10) + "-" + strconv.FormatInt(fi.Size(), 10), nil
+}
+
+// FilePath find the template file path by name, returns empty on not found.
+//
+// The name must be a valid relative path in the Dir. eg: "../secret" is invalid.
+func (l *FileLoader) FilePath(name string) string {
+	if !fs.ValidPath(filepath.ToSlash(name)) {
+		return ""
+	}
+
+	base := filepath.Join(l.Dir, filepath.FromSlash(name))
+	if l.isValidExt(filepath.Ext(name)) && isFile(base) {
+		return base
+	}
+
+	for _, ext := range l.ExtNames {
+		if path := base + "." + strings.TrimPrefix(ext, "."); isFile(path) {
+			return path
+		}
+	}
+	return ""
+}
+
+func (l *FileLoader) isValidExt(ext string) bool {
+	if ext == "" {
+		return false
+	}
+
+	for _, name := range l.ExtNames {
+		if strings.TrimPrefix(name, ".") == ext[1:] {
+			return true
+		}
+	}
+	return false
+}
+
+func isFile(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && !fi.IsDir()
+}
+
+/*************************************************************
+ * memory loader
+ *************************************************************/
+
+// MemoryLoader load the templates from memory. useful for tests and as a reference of custom loader.
+type MemoryLoader struct {
+	mu   sync.RWMutex
+	tpls map[string]memTemplate
+	// ver the last version, increase on each Set()
+	ver uint64
+}
+
+type memTemplate struct {
+	src []byte
+	ver uint64
+}
+
+// NewMemoryLoader create a new memory loader with templates. key is name, value is contents.
+func NewMemoryLoader(tpls map[string]string) *MemoryLoader {
+	l := &MemoryLoader{tpls: make(map[string]memTemplate, len(tpls))}
+	for name, text := range tpls {
+		l.Set(name, text)
+	}
+	return l
+}
+
+// Set add or update the template contents, will change the version.
+func (l *MemoryLoader) Set(name, text string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.ver++
+	l.tpls[name] = memTemplate{src: []byte(text), ver: l.ver}
+}
+
+// Delete the template by name
+func (l *MemoryLoader) Delete(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.tpls, name)
+}
+
+// Names list all template names, sorted.
+func (l *MemoryLoader) Names() ([]string, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	names := make([]string, 0, len(l.tpls))
+	for name := range l.tpls {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// Read the template contents by name
+func (l *MemoryLoader) Read(name string) ([]byte, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if t, ok := l.tpls[name]; ok {
+		return t.src, nil
+	}
+	return nil, errNotFound(name)
+}
+
+// Version get the version of the template
+func (l *MemoryLoader) Version(name string) (string, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if t, ok := l.tpls[name]; ok {
+		return strconv.FormatUint(t.ver, 10), nil
+	}
+	return "", errNotFound(name)
+}
+
+/*************************************************************
+ * use loader on renderer
+ *************************************************************/
+
+// readLoader read the template and its version from the Loader.
+func (r *Renderer) readLoader(name string) (bs []byte, ver string, err error) {
+	// get version first, if modified between the two calls, will reload on next check.
+	if ver, err = r.Loader.Version(name); err != nil {
+		return nil, "", err
+	}
+
+	bs, err = r.Loader.Read(name)
+	return bs, ver, err
+}
+
+// compileLoader load all templates from the Loader on initialize.
+func (r *Renderer) compileLoader() error {
+	names, err := r.Loader.Names()
+	if err != nil {
+		return errorx.Ef("easytpl: list the templates from loader failed: %w", err)
+	}
+
+	r.logDebug("easytpl: compile templates from the loader", "templates", len(names))
+	for _, name := range names {
+		bs, ver, err := r.readLoader(name)
+		if err != nil {
+			return errorx.Ef("easytpl: read the template %q from loader failed: %w", name, err)
+		}
+
+		r.loadBytes(name, bs, r.EnableExtends)
+		r.setLoaded(name, ver)
+	}
+	return nil
+}
+
+// setLoaded record the template version from the Loader, and the file path if it has.
+func (r *Renderer) setLoaded(name, ver string) {
+	r.setMu.Lock()
+	defer r.setMu.Unlock()
+
+	if r.versions == nil {
+		r.versions = make(map[string]string)
+	}
+	r.versions[name] = ver
+
+	if fl, ok := r.Loader.(interface{ FilePath(string) string }); ok {
+		if path := fl.FilePath(name); path != "" {
+			r.fileMap[name] = path
+		}
+	}
+}
+
+// loadedVersion get the recorded version of the template loaded from the Loader.
+func (r *Renderer) loadedVersion(name string) (string, bool) {
+	r.setMu.RLock()
+	defer r.setMu.RUnlock()
+
+	ver, ok := r.versions[name]
+	return ver, ok
+}
+
+// reloadLoader read the template from the Loader, rebuild the templates with it.
+func (r *Renderer) reloadLoader(name string) error {
+	bs, ver, err := r.readLoader(name)
+	if err != nil {
+		return err
+	}
+
+	if err := r.rebuild(name, bs, false); err != nil {
+		return err
+	}
+	r.setLoaded(name, ver)
+	return nil
+}
+
+// lazyMissTTL the not found names on lazy load are cached for the duration. see findTemplate()
+const lazyMissTTL = 3 * time.Second
+
+// lazyMisses the not found names on lazy load from the Loader. value is the expire time.
+type lazyMisses struct {
+	mu    sync.Mutex
+	names map[string]time.Time
+}
+
+func (lm *lazyMisses) has(name string) bool {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	exp, ok := lm.names[name]
+	if ok && time.Now().After(exp) {
+		delete(lm.names, name)
+		return false
+	}
+	return ok
+}
+
+func (lm *lazyMisses) add(name string) {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	if lm.names == nil {
+		lm.names = make(map[string]time.Time)
+	}
+	lm.names[name] = time.Now().Add(lazyMissTTL)
+}
+
+func (lm *lazyMisses) reset() {
+	lm.mu.Lock()
+	lm.names = nil
+	lm.mu.Unlock()
+}
+
+// findTemplate get the template by name, on not found will lazy load it from the Loader
+// when Options.AutoSearchFile is enabled.
+//
+// Returns nil and no error on not found, the not found name is cached for a short time.
+func (r *Renderer) findTemplate(name string) (*template.Template, error) {
+	tpl := r.master(name)
+	if tpl != nil || !r.AutoSearchFile || r.Loader == nil || r.misses.has(name) {
+		return tpl, nil
+	}
+
+	for _, key := range []string{r.cleanExt(name), name} {
+		err := r.lazyLoad(key)
+		if err == nil {
+			r.logDebug("easytpl: lazy load the template from loader", "name", key)
+			return r.master(name), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, errorx.Ef("easytpl: lazy load the template %q failed: %w", key, err)
+		}
+	}
+
+	r.misses.add(name)
+	return nil, nil
+}
+
+// lazyLoad read the template from the Loader and add it to the templates set, without rebuild others.
+func (r *Renderer) lazyLoad(name string) error {
+	bs, ver, err := r.readLoader(name)
+	if err != nil {
+		return err
+	}
+
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+
+	// maybe loaded by other goroutine
+	if r.master(name) != nil {
+		return nil
+	}
+
+	r.setMu.RLock()
+	b := &Renderer{
+		Options:     r.Options,
+		init:        true,
+		blockRe:     r.blockRe,
+		extMap:      r.extMap,
+		root:        template.Must(r.root.Clone()),
+		fileMap:     maps.Clone(r.fileMap),
+		layoutOf:    maps.Clone(r.layoutOf),
+		baseTpl:     maps.Clone(r.baseTpl),
+		withExtends: maps.Clone(r.withExtends),
+		sources:     maps.Clone(r.sources),
+		srcNames:    slices.Clone(r.srcNames),
+	}
+	r.setMu.RUnlock()
+
+	if err := catchPanic(func() { b.loadBytes(name, bs, false) }); err != nil {
+		return err
+	}
+
+	r.setMu.Lock()
+	r.root, r.withExtends = b.root, b.withExtends
+	r.fileMap, r.layoutOf, r.baseTpl = b.fileMap, b.layoutOf, b.baseTpl
+	r.sources, r.srcNames = b.sources, b.srcNames
+	r.gen.Add(1)
+	r.setMu.Unlock()
+
+	r.setLoaded(name, ver)
+	return nil
+}
+
+// ReloadChanged check the templates by the Loader version. will reload the modified templates,
+// remove the deleted templates and load the new added templates.
+//
+// Returns the changed template names.
+func (r *Renderer) ReloadChanged() ([]string, error) {
+	r.requireInit("please call Init() before reload templates")
+	if r.Loader == nil {
+		return nil, errorx.E("easytpl: the Loader is not set for check reload")
+	}
+
+	names, err := r.Loader.Names()
+	if err != nil {
+		return nil, errorx.Ef("easytpl: list the templates from loader failed: %w", err)
+	}
+
+	r.misses.reset()
+	r.setMu.RLock()
+	loaded := make(map[string]string, len(r.versions))
+	for name, ver := range r.versions {
+		loaded[name] = ver
+	}
+	r.setMu.RUnlock()
+
+	var changed []string
+	for _, name := range names {
+		ver, err := r.Loader.Version(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // deleted after list, will remove it below
+		}
+		if err != nil {
+			return changed, err
+		}
+
+		old, ok := loaded[name]
+		delete(loaded, name)
+		if !ok || ver != old {
+			if err := r.reloadLoader(name); err != nil {
+				return changed, err
+			}
+			changed = append(changed, name)
+		}
+	}
+
+	// the remaining are deleted from the Loader
+	removed := maputil.Keys(loaded)
+	sort.Strings(removed)
+
+	for _, name := range removed {
+		if err := r.Remove(name); err != nil {
+			return changed, err
+		}
+		changed = append(changed, name)
+	}
+	return changed, nil
+}
@@ -0,0 +1,139 @@
+package easytpl_test
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gookit/easytpl"
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+func TestMemoryLoader(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	l := easytpl.NewMemoryLoader(map[string]string{
+		"layout": `[{{ yield }}]`,
+		"home":   `home: {{ . }}, {{ include "footer" }}`,
+		"footer": `footer`,
+	})
+	r := easytpl.NewInited(easytpl.WithLoader(l), easytpl.WithLayout("layout"))
+
+	is.NoErr(r.Render(bf, "home", "tom"))
+	is.Eq("[home: tom, footer]", bf.String())
+
+	// no changes
+	changed, err := r.ReloadChanged()
+	is.NoErr(err)
+	is.Empty(changed)
+
+	l.Set("footer", `new footer`)
+	l.Set("about", `about`)
+	l.Delete("layout")
+	r.DisableLayout = true
+
+	changed, err = r.ReloadChanged()
+	is.NoErr(err)
+	is.Eq([]string{"about", "footer", "layout"}, changed)
+	is.Nil(r.Template("layout"))
+
+	bf.Reset()
+	is.NoErr(r.Render(bf, "home", "tom"))
+	is.Eq("home: tom, new footer", bf.String())
+
+	// reload one
+	l.Set("about", `new about`)
+	is.NoErr(r.Reload("about"))
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "about", nil))
+	is.Eq("new about", bf.String())
+
+	_, err = l.Read("not-exist")
+	is.True(errors.Is(err, fs.ErrNotExist))
+}
+
+func TestLoader_lazyLoad(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	l := easytpl.NewMemoryLoader(map[string]string{
+		"home": `home, {{ include "part" }}`,
+	})
+	r := easytpl.NewInited(easytpl.WithLoader(l), func(r *easytpl.Renderer) {
+		r.AutoSearchFile = true
+	})
+
+	// not found on render
+	is.ErrSubMsg(r.Execute(bf, "home", nil), `the include template "part" is not found`)
+
+	l.Set("part", `part`)
+	l.Set("about", `about`)
+	l.Set("bad", `{{ bad`)
+
+	// the not found name is cached for a short time
+	bf.Reset()
+	is.ErrSubMsg(r.Execute(bf, "home", nil), `the include template "part" is not found`)
+
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "about.tpl", nil))
+	is.Eq("about", bf.String())
+
+	// returns the load error
+	is.ErrSubMsg(r.Execute(bf, "bad", nil), `lazy load the template "bad" failed: template: bad:1: function "bad" not defined`)
+
+	l.Delete("bad")
+	_, err := r.ReloadChanged()
+	is.NoErr(err)
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "home", nil))
+	is.Eq("home, part", bf.String())
+}
+
+func TestFileLoader_FilePath(t *testing.T) {
+	is := assert.New(t)
+	dir := t.TempDir()
+	is.NoErr(os.WriteFile(filepath.Join(dir, "home.tpl"), []byte("home"), 0644))
+	is.NoErr(os.WriteFile(filepath.Join(filepath.Dir(dir), "secret.tpl"), []byte("secret"), 0644))
+
+	l := easytpl.NewFileLoader(dir)
+	is.Eq(filepath.Join(dir, "home.tpl"), l.FilePath("home"))
+	is.Eq(filepath.Join(dir, "home.tpl"), l.FilePath("home.tpl"))
+
+	// cannot escape the dir
+	for _, name := range []string{"../secret", "/etc/passwd", "a/../../secret", "./home", ""} {
+		is.Empty(l.FilePath(name), name)
+	}
+	_, err := l.Read("../secret")
+	is.ErrSubMsg(err, `the template "../secret" is not found`)
+}
+
+func TestFileLoader(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	dir := t.TempDir()
+	is.NoErr(os.MkdirAll(filepath.Join(dir, "admin"), 0755))
+	is.NoErr(os.WriteFile(filepath.Join(dir, "admin/home.tpl"), []byte(`admin home`), 0644))
+	is.NoErr(os.WriteFile(filepath.Join(dir, "hello.html"), []byte(`hello {{ . }}`), 0644))
+	is.NoErr(os.WriteFile(filepath.Join(dir, "readme.md"), []byte(`readme`), 0644))
+
+	l := easytpl.NewFileLoader(dir)
+	names, err := l.Names()
+	is.NoErr(err)
+	is.Eq([]string{"admin/home", "hello"}, names)
+	is.Eq(filepath.Join(dir, "hello.html"), l.FilePath("hello"))
+	is.Eq(filepath.Join(dir, "hello.html"), l.FilePath("hello.html"))
+	is.Eq("", l.FilePath("readme"))
+
+	_, err = l.Version("readme")
+	is.True(errors.Is(err, fs.ErrNotExist))
+
+	r := easytpl.NewInited(easytpl.WithLoader(l))
+	is.NoErr(r.Execute(bf, "hello", "tom"))
+	is.Eq("hello tom", bf.String())
+	is.Eq(filepath.Join(dir, "admin/home.tpl"), r.TemplateFiles()["admin/home"])
+}
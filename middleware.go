@@ -0,0 +1,45 @@
+package easytpl
+
+import (
+	"context"
+	"io"
+)
+
+// RenderFunc the render func for middlewares.
+//
+// layout is same as the Render() layout argument, for Execute/Partial it is "" (disable layout).
+type RenderFunc func(ctx context.Context, w io.Writer, name string, data any, layout ...string) error
+
+// Middleware wrap the RenderFunc for cross-cutting concerns. eg: tracing, recover panics.
+type Middleware func(next RenderFunc) RenderFunc
+
+// Use add render middlewares, will be applied in order to every Render/Execute/Partial call.
+// the first added is the outermost.
+//
+// NOTE: please add middlewares before rendering, it is not safe for concurrent use.
+//
+// Usage:
+//
+//	r.Use(func(next easytpl.RenderFunc) easytpl.RenderFunc {
+//		return func(ctx context.Context, w io.Writer, name string, data any, layout ...string) error {
+//			ctx, span := tracer.Start(ctx, "render "+name)
+//			defer span.End()
+//			return next(ctx, w, name, data, layout...)
+//		}
+//	})
+func (r *Renderer) Use(mws ...Middleware) {
+	r.middlewares = append(r.middlewares, mws...)
+
+	fn := RenderFunc(r.render)
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		fn = r.middlewares[i](fn)
+	}
+	r.renderFn = fn
+}
+
+func (r *Renderer) renderFunc() RenderFunc {
+	if r.renderFn != nil {
+		return r.renderFn
+	}
+	return r.render
+}
@@ -0,0 +1,81 @@
+package easytpl_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gookit/easytpl"
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+func TestRenderer_Use(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	var calls []string
+	logMw := func(tag string) easytpl.Middleware {
+		return func(next easytpl.RenderFunc) easytpl.RenderFunc {
+			return func(ctx context.Context, w io.Writer, name string, data any, layout ...string) error {
+				calls = append(calls, tag+":"+name+":"+strings.Join(layout, ","))
+				return next(ctx, w, name, data, layout...)
+			}
+		}
+	}
+
+	// recover panics to errors
+	recoverMw := func(next easytpl.RenderFunc) easytpl.RenderFunc {
+		return func(ctx context.Context, w io.Writer, name string, data any, layout ...string) (err error) {
+			defer func() {
+				if e := recover(); e != nil {
+					err = fmt.Errorf("render panic: %v", e)
+				}
+			}()
+			return next(ctx, w, name, data, layout...)
+		}
+	}
+
+	// override the template name
+	abMw := func(next easytpl.RenderFunc) easytpl.RenderFunc {
+		return func(ctx context.Context, w io.Writer, name string, data any, layout ...string) error {
+			if name == "home" {
+				name = "home-b"
+			}
+			return next(ctx, w, name, data, layout...)
+		}
+	}
+
+	r := easytpl.NewInited()
+	r.Use(logMw("a"), recoverMw)
+	r.Use(logMw("b"), abMw)
+	r.LoadStrings(map[string]string{
+		"layout": `[{{ yield }}]`,
+		"home":   `home {{ . }}`,
+		"home-b": `home-b {{ . }}`,
+	})
+
+	is.NoErr(r.Render(bf, "home", "tom", "layout"))
+	is.Eq("[home-b tom]", bf.String())
+	is.Eq([]string{"a:home:layout", "b:home:layout"}, calls)
+
+	calls = calls[:0]
+	bf.Reset()
+	is.NoErr(r.Partial(bf, "home", "tom"))
+	is.Eq("home-b tom", bf.String())
+	is.Eq([]string{"a:home:", "b:home:"}, calls)
+
+	err := r.Render(bf, "home", "tom", "not-exist")
+	is.ErrSubMsg(err, `the layout template "not-exist" is not found`)
+
+	// std functions
+	defer easytpl.Revert()
+	easytpl.Use(abMw)
+	easytpl.Initialize()
+	easytpl.LoadStrings(map[string]string{"home": `home`, "home-b": `home-b`})
+	bf.Reset()
+	is.NoErr(easytpl.Execute(bf, "home", nil))
+	is.Eq("home-b", bf.String())
+}
@@ -0,0 +1,247 @@
+package easytpl
+
+import (
+	"bytes"
+	"io"
+)
+
+// MinifyHTML minify the HTML contents.
+//
+//   - collapse insignificant whitespace to one space
+//   - strip comments, but keep the conditional comments. eg: <!--[if IE]> ... <![endif]-->
+//   - keep the contents of <pre>, <textarea>, <script> and <style> intact
+func MinifyHTML(src []byte) []byte {
+	buf := new(bytes.Buffer)
+	mw := NewMinifyWriter(buf)
+	_, _ = mw.Write(src)
+	_ = mw.Close()
+	return buf.Bytes()
+}
+
+// minify states
+const (
+	mText uint8 = iota
+	mTag
+	mComment
+	mKeepComment
+	mRaw
+)
+
+// the elements that contents must be kept intact
+var rawElements = map[string]bool{"pre": true, "textarea": true, "script": true, "style": true}
+
+// the conditional comments start marks
+var (
+	condCommentStart = []byte("<!--[if")
+	condCommentEnd   = []byte("<!--<![endif]")
+)
+
+// MinifyWriter minify the HTML contents on writing, can be used for streaming output.
+//
+// NOTE: must call Close() after all contents written, for flush the pending contents.
+type MinifyWriter struct {
+	w io.Writer
+	// pending input that needs more bytes to decide
+	in  []byte
+	out []byte
+
+	state uint8
+	// quote char in the tag
+	quote byte
+	// has pending whitespace for output
+	space bool
+	// has some content output
+	started bool
+	// collecting tag name, tagName is collected name
+	inName  bool
+	tagName []byte
+	// the current raw element name. eg: "pre"
+	rawTag []byte
+}
+
+// NewMinifyWriter create a minify writer
+func NewMinifyWriter(w io.Writer) *MinifyWriter {
+	return &MinifyWriter{w: w}
+}
+
+// Write contents for minify
+func (m *MinifyWriter) Write(p []byte) (int, error) {
+	m.in = append(m.in, p...)
+	if err := m.process(false); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close flush all pending contents
+func (m *MinifyWriter) Close() error {
+	return m.process(true)
+}
+
+// process the pending input. if final is false, will keep the undecidable tail for next write.
+func (m *MinifyWriter) process(final bool) error {
+	in := m.in
+	i := 0
+
+loop:
+	for i < len(in) {
+		c := in[i]
+		switch m.state {
+		case mText:
+			if isSpace(c) {
+				m.space = true
+				i++
+				continue
+			}
+			if c != '<' {
+				m.emitText(c)
+				i++
+				continue
+			}
+
+			// need more bytes for check comment
+			rest := in[i:]
+			if !final && (isPrefixOf(rest, condCommentStart) || isPrefixOf(rest, condCommentEnd)) {
+				break loop
+			}
+
+			if bytes.HasPrefix(rest, []byte("<!--")) {
+				if bytes.HasPrefix(rest, condCommentStart) || bytes.HasPrefix(rest, condCommentEnd) {
+					m.emitText('<')
+					m.out = append(m.out, "!--"...)
+					m.state = mKeepComment
+				} else {
+					m.state = mComment
+				}
+				i += 4
+				continue
+			}
+
+			m.emitText('<')
+			m.state, m.inName = mTag, true
+			m.tagName = m.tagName[:0]
+			i++
+		case mTag:
+			i++
+			if m.quote != 0 {
+				if c == m.quote {
+					m.quote = 0
+				}
+				m.out = append(m.out, c)
+				continue
+			}
+
+			if m.inName && (isNameChar(c) || (c == '/' && len(m.tagName) == 0)) {
+				m.tagName = append(m.tagName, lowerASCII(c))
+				m.out = append(m.out, c)
+				continue
+			}
+			m.inName = false
+
+			switch {
+			case isSpace(c):
+				m.space = true
+			case c == '>':
+				m.space = false
+				m.out = append(m.out, c)
+				m.state = mText
+				if rawElements[string(m.tagName)] {
+					m.state = mRaw
+					m.rawTag = append(m.rawTag[:0], m.tagName...)
+				}
+			default:
+				if c == '"' || c == '\'' {
+					m.quote = c
+				}
+				if m.space {
+					m.out = append(m.out, ' ')
+					m.space = false
+				}
+				m.out = append(m.out, c)
+			}
+		case mComment, mKeepComment:
+			rest := in[i:]
+			end := bytes.Index(rest, []byte("-->"))
+			if end < 0 {
+				// keep the last 2 bytes for check the end mark on next write
+				n := len(rest)
+				if !final {
+					n = max(n-2, 0)
+				}
+				if m.state == mKeepComment {
+					m.out = append(m.out, rest[:n]...)
+				}
+				i += n
+				break loop
+			}
+
+			if m.state == mKeepComment {
+				m.out = append(m.out, rest[:end+3]...)
+			}
+			m.state = mText
+			i += end + 3
+		case mRaw:
+			rest := in[i:]
+			if c != '<' {
+				m.out = append(m.out, c)
+				i++
+				continue
+			}
+
+			// check is the end tag. eg: "</pre"
+			n := len(m.rawTag) + 2
+			if len(rest) < n && !final {
+				break loop
+			}
+			if len(rest) >= n && rest[1] == '/' && bytes.EqualFold(rest[2:n], m.rawTag) {
+				m.out = append(m.out, rest[:n]...)
+				m.tagName = append(m.tagName[:0], '/')
+				m.tagName = append(m.tagName, m.rawTag...)
+				m.state, m.inName = mTag, false
+				i += n
+				continue
+			}
+			m.out = append(m.out, c)
+			i++
+		}
+	}
+
+	// keep the unprocessed bytes
+	m.in = append(m.in[:0], in[i:]...)
+	if len(m.out) == 0 {
+		return nil
+	}
+
+	_, err := m.w.Write(m.out)
+	m.out = m.out[:0]
+	return err
+}
+
+// emitText write a text char, will write the pending whitespace before it.
+func (m *MinifyWriter) emitText(c byte) {
+	if m.space && m.started {
+		m.out = append(m.out, ' ')
+	}
+	m.space, m.started = false, true
+	m.out = append(m.out, c)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isNameChar(c byte) bool {
+	return isASCIILetter(c) || (c >= '0' && c <= '9') || c == '-' || c == ':'
+}
+
+func lowerASCII(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + ('a' - 'A')
+	}
+	return c
+}
+
+// isPrefixOf check s is a proper prefix of the mark
+func isPrefixOf(s, mark []byte) bool {
+	return len(s) < len(mark) && bytes.HasPrefix(mark, s)
+}
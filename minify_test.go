@@ -0,0 +1,61 @@
+package easytpl_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gookit/easytpl"
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+func TestMinifyHTML(t *testing.T) {
+	src := `
+<div  class="box   main"
+   id="app">
+	<!-- comment -->
+	<p>hello,   <b>tom</b>
+	</p>
+	<!--[if IE]><p>old browser</p><![endif]-->
+	<PRE>  keep
+	  this  </PRE>
+	<textarea> a  b </textarea>
+	<script>if (a  <  b) { x = "<!-- no -->"; }</script>
+	<style> .a  { color: red; } </style>
+</div>
+`
+	want := `<div class="box   main" id="app"> <p>hello, <b>tom</b> </p> <!--[if IE]><p>old browser</p><![endif]--> <PRE>  keep
+	  this  </PRE> <textarea> a  b </textarea> <script>if (a  <  b) { x = "<!-- no -->"; }</script> <style> .a  { color: red; } </style> </div>`
+
+	assert.Eq(t, want, string(easytpl.MinifyHTML([]byte(src))))
+
+	// streaming: write byte by byte
+	buf := new(bytes.Buffer)
+	mw := easytpl.NewMinifyWriter(buf)
+	for i := 0; i < len(src); i++ {
+		_, err := mw.Write([]byte{src[i]})
+		assert.NoErr(t, err)
+	}
+	assert.NoErr(t, mw.Close())
+	assert.Eq(t, want, buf.String())
+
+	// unclosed comment
+	assert.Eq(t, "a", string(easytpl.MinifyHTML([]byte("a <!-- not closed"))))
+}
+
+func TestRenderer_Minify(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewInited(easytpl.WithMinify)
+	r.LoadStrings(map[string]string{
+		"layout": "<html>\n  <body>\n    {{ yield }}\n  </body>\n</html>\n",
+		"home":   "<!-- home -->\n<h1>  {{ . }}  </h1>\n<pre>\n  code\n</pre>",
+	})
+
+	is.NoErr(r.Render(bf, "home", "hi", "layout"))
+	is.Eq("<html> <body> <h1> hi </h1> <pre>\n  code\n</pre> </body> </html>", bf.String())
+
+	bf.Reset()
+	is.NoErr(r.String(bf, "<p>\n  {{ . }}\n</p>  <!-- end -->", "hi"))
+	is.Eq("<p> hi </p>", bf.String())
+}
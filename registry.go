@@ -0,0 +1,163 @@
+package easytpl
+
+import (
+	"context"
+	"html/template"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/gookit/goutil/errorx"
+)
+
+// Registry holds multiple named renderers. eg: "site", "admin", "email"
+//
+// The renderers in a registry can include the templates from each other by
+// the func includeFrom. eg: {{ includeFrom "site" "partials/footer" . }}
+type Registry struct {
+	mu        sync.RWMutex
+	renderers map[string]*Renderer
+}
+
+// NewRegistry create a new renderer registry.
+//
+// Usage:
+//
+//	reg := easytpl.NewRegistry()
+//	reg.Add("site", easytpl.New(easytpl.WithTplDirs("views/site")))
+//	reg.Add("email", easytpl.New(easytpl.WithTplDirs("views/email"), easytpl.DisableLayout))
+//	reg.MustInit()
+//
+//	reg.Render("email", w, "welcome", data)
+func NewRegistry() *Registry {
+	return &Registry{renderers: make(map[string]*Renderer)}
+}
+
+// Add a named renderer to the registry. will panic on the name exists,
+// or the renderer already added to other registry.
+func (rg *Registry) Add(name string, r *Renderer) *Registry {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	if _, ok := rg.renderers[name]; ok {
+		panicf("the renderer %q already exists in the registry", name)
+	}
+	if r.registry != nil && r.registry != rg {
+		panicf("the renderer %q already added to other registry", name)
+	}
+
+	r.registry = rg
+	rg.renderers[name] = r
+	return rg
+}
+
+// Get a renderer by name, if not exists, return nil
+func (rg *Registry) Get(name string) *Renderer {
+	rg.mu.RLock()
+	defer rg.mu.RUnlock()
+	return rg.renderers[name]
+}
+
+// MustGet get a renderer by name, will panic on not exists.
+func (rg *Registry) MustGet(name string) *Renderer {
+	if r := rg.Get(name); r != nil {
+		return r
+	}
+	panicf("the renderer %q is not found in the registry", name)
+	return nil
+}
+
+// Names returns the names of the renderers, sorted.
+func (rg *Registry) Names() []string {
+	rg.mu.RLock()
+	defer rg.mu.RUnlock()
+
+	names := make([]string, 0, len(rg.renderers))
+	for name := range rg.renderers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Init initialize all renderers in the registry, stop on the first error.
+func (rg *Registry) Init() error {
+	for _, name := range rg.Names() {
+		if err := rg.Get(name).Init(); err != nil {
+			return errorx.Ef("easytpl: initialize the renderer %q failed: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// MustInit initialize all renderers in the registry, will panic on error
+func (rg *Registry) MustInit() *Registry {
+	panicErr(rg.Init())
+	return rg
+}
+
+// Render a template by the named renderer, write result to the Writer. see Renderer.Render()
+func (rg *Registry) Render(name string, w io.Writer, tplName string, v any, layout ...string) error {
+	return rg.RenderContext(context.Background(), name, w, tplName, v, layout...)
+}
+
+// RenderContext render a template by the named renderer, see Renderer.RenderContext()
+func (rg *Registry) RenderContext(ctx context.Context, name string, w io.Writer, tplName string, v any, layout ...string) error {
+	r, err := rg.renderer(name)
+	if err != nil {
+		return err
+	}
+	return r.RenderContext(ctx, w, tplName, v, layout...)
+}
+
+// Execute render partial by the named renderer, will not render layout file
+func (rg *Registry) Execute(name string, w io.Writer, tplName string, v any) error {
+	r, err := rg.renderer(name)
+	if err != nil {
+		return err
+	}
+	return r.Execute(w, tplName, v)
+}
+
+func (rg *Registry) renderer(name string) (*Renderer, error) {
+	if r := rg.Get(name); r != nil {
+		return r, nil
+	}
+	return nil, errorx.Ef("easytpl: the renderer %q is not found in the registry", name)
+}
+
+// includeFrom include a template from other renderer in the same registry.
+// the render state is shared, so the limits and the pushed stacks also apply to it.
+//
+// Usage:
+//
+//	{{ includeFrom "site" "partials/footer" }}
+//	{{ includeFrom "site" "partials/footer" . }}
+func (es *execSet) includeFrom(name, tplName string, data ...any) (template.HTML, error) {
+	r := es.r
+	if r.registry == nil {
+		return "", errorx.Ef("includeFrom called on the renderer not in a registry, want include: %s", tplName)
+	}
+	if r.Sandbox {
+		return "", errorx.Ef("includeFrom the renderer %q is not allowed on sandbox mode", name)
+	}
+
+	other, err := r.registry.renderer(name)
+	if err != nil {
+		return "", err
+	}
+	if other == r {
+		return es.include(tplName, data...)
+	}
+	if !other.init {
+		return "", errorx.Ef("easytpl: the renderer %q is not initialized", name)
+	}
+
+	oes, err := other.getSet(es.st)
+	if err != nil {
+		return "", err
+	}
+	defer other.putSet(oes)
+	return oes.include(tplName, data...)
+}
@@ -0,0 +1,67 @@
+package easytpl_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gookit/easytpl"
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	site := easytpl.New(easytpl.WithLayout("layout"))
+	email := easytpl.New(func(r *easytpl.Renderer) {
+		r.Delims = easytpl.TplDelims{Left: "[[", Right: "]]"}
+	})
+
+	reg := easytpl.NewRegistry().Add("site", site).Add("email", email)
+	is.NoErr(reg.Init())
+	is.Eq([]string{"email", "site"}, reg.Names())
+
+	site.LoadStrings(map[string]string{
+		"layout":          `[{{ yield }}]`,
+		"home":            `home: {{ .name }}`,
+		"partials/footer": `footer: {{ .name }}{{ push "js" }}a.js{{ end }}`,
+	})
+	email.LoadStrings(map[string]string{
+		"welcome": `hi [[ .name ]], [[ includeFrom "site" "partials/footer" . ]]|[[ stack "js" ]]`,
+		"self":    `[[ includeFrom "email" "welcome" . ]]`,
+		"missing": `[[ includeFrom "admin" "home" ]]`,
+	})
+
+	data := map[string]any{"name": "tom"}
+	is.NoErr(reg.Render("site", bf, "home", data))
+	is.Eq("[home: tom]", bf.String())
+
+	bf.Reset()
+	is.NoErr(reg.Render("email", bf, "welcome", data))
+	is.Eq("hi tom, footer: tom|a.js", bf.String())
+
+	bf.Reset()
+	is.NoErr(reg.Execute("email", bf, "self", data))
+	is.Eq("hi tom, footer: tom|a.js", bf.String())
+
+	bf.Reset()
+	is.ErrSubMsg(reg.Render("email", bf, "missing", nil), `the renderer "admin" is not found`)
+	is.ErrSubMsg(reg.Render("admin", bf, "home", nil), `the renderer "admin" is not found`)
+
+	is.Panics(func() {
+		reg.Add("site", easytpl.New())
+	})
+	is.Panics(func() {
+		easytpl.NewRegistry().Add("site", site)
+	})
+	is.Nil(reg.Get("admin"))
+	is.Eq(site, reg.MustGet("site"))
+}
+
+func TestRenderer_includeFrom_noRegistry(t *testing.T) {
+	r := easytpl.NewInited()
+	r.LoadString("page", `{{ includeFrom "site" "home" }}`)
+
+	err := r.Execute(new(bytes.Buffer), "page", nil)
+	assert.ErrSubMsg(t, err, "not in a registry")
+}
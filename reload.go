@@ -0,0 +1,383 @@
+package easytpl
+
+import (
+	"fmt"
+	"html/template"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gookit/goutil/errorx"
+	"github.com/gookit/goutil/maputil"
+)
+
+// Reload re-read the template file by name, rebuild it and the dependent templates.
+//
+// Only available for the templates loaded from file or the Loader. see Replace() for more details.
+func (r *Renderer) Reload(name string) error {
+	key, _ := r.sourceKey(name)
+	if _, ok := r.loadedVersion(key); ok {
+		r.logDebug("easytpl: reload template from loader", "name", key)
+		return r.reloadLoader(key)
+	}
+
+	file := r.fileOf(key)
+	if file == "" {
+		return errorx.Ef("easytpl: the template %q is not loaded from file", name)
+	}
+
+	bs, err := os.ReadFile(file)
+	if err != nil {
+		return errorx.Ef("easytpl: reload the template %q failed: %w", name, err)
+	}
+
+	r.logDebug("easytpl: reload template file", "name", key, "path", file)
+	return r.rebuild(key, bs, false)
+}
+
+// Replace the template contents by name, if not exists will add it.
+//
+// The templates are rebuilt then swapped atomically, so it is safe to call it on rendering.
+// On error, the old templates will keep serving.
+//
+// Rebuild the templates:
+//   - all templates in the root, since they share the namespace. eg: {{ template "name" }}
+//   - all templates with extends, since they are cloned from the base with the root namespace.
+//   - the cached fragments of the changed one and its extends dependents are invalidated.
+func (r *Renderer) Replace(name, tplText string) error {
+	key, _ := r.sourceKey(name)
+	r.logDebug("easytpl: replace template text", "name", key)
+	return r.rebuild(key, []byte(tplText), false)
+}
+
+// Remove the template by name. will return error if other templates extends it or use it as layout.
+//
+// It is safe to call it on rendering. see Replace() for more details.
+func (r *Renderer) Remove(name string) error {
+	key, ok := r.sourceKey(name)
+	if !ok {
+		return errorx.Ef("easytpl: the template %q is not found", name)
+	}
+
+	r.setMu.RLock()
+	deps := r.extendsDependents(key)
+	users := r.layoutUsers(key)
+	r.setMu.RUnlock()
+
+	delete(deps, key)
+	if len(deps) > 0 {
+		names := maputil.Keys(deps)
+		sort.Strings(names)
+		return errorx.Ef("easytpl: the template %q is extended by: %s", name, strings.Join(names, ", "))
+	}
+	if len(users) > 0 {
+		return errorx.Ef("easytpl: the template %q is used as layout by: %s", name, strings.Join(users, ", "))
+	}
+
+	r.logDebug("easytpl: remove template", "name", key)
+	return r.rebuild(key, nil, true)
+}
+
+// sourceKey get the loaded source name of the template. resolve rules same as Template()
+func (r *Renderer) sourceKey(name string) (string, bool) {
+	r.setMu.RLock()
+	defer r.setMu.RUnlock()
+
+	for _, key := range []string{r.cleanExt(name), name} {
+		if _, ok := r.sources[key]; ok {
+			return key, true
+		}
+	}
+	return name, false
+}
+
+// extendsDependents find the templates extends the template, directly or indirectly. include itself.
+func (r *Renderer) extendsDependents(name string) map[string]bool {
+	deps := map[string]bool{name: true}
+	for found := true; found; {
+		found = false
+		for tplName, base := range r.baseTpl {
+			if !deps[tplName] && (deps[base] || deps[r.cleanExt(base)]) {
+				deps[tplName] = true
+				found = true
+			}
+		}
+	}
+	return deps
+}
+
+// rebuild the templates set with the changed template, then swap it to the renderer.
+func (r *Renderer) rebuild(name string, bs []byte, remove bool) error {
+	r.requireInit("please call Init() before reload templates")
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+
+	r.setMu.RLock()
+	deps := r.extendsDependents(name)
+	b := &Renderer{
+		Options:  r.Options,
+		init:     true,
+		blockRe:  r.blockRe,
+		extMap:   r.extMap,
+		fileMap:  maps.Clone(r.fileMap),
+		layoutOf: maps.Clone(r.layoutOf),
+		sources:  maps.Clone(r.sources),
+		srcNames: slices.Clone(r.srcNames),
+	}
+	r.setMu.RUnlock()
+
+	if r.EnableExtends {
+		b.baseTpl = make(map[string]string)
+		b.waitBase = make(map[string][]byte)
+		b.withExtends = make(map[string]*template.Template)
+	}
+	if remove {
+		delete(b.sources, name)
+		delete(b.fileMap, name)
+		delete(b.layoutOf, r.cleanExt(name))
+		b.srcNames = slices.DeleteFunc(b.srcNames, func(s string) bool { return s == name })
+	} else {
+		b.addSource(name, bs)
+	}
+
+	err := catchPanic(func() {
+		b.ensureRoot()
+		for _, tplName := range b.srcNames {
+			b.loadBytes(tplName, b.sources[tplName], b.EnableExtends)
+		}
+		b.loadWaitBase()
+	})
+	if err != nil {
+		return errorx.Ef("easytpl: rebuild the templates for %q failed: %w", name, err)
+	}
+
+	// bind the funcs to the renderer
+	b.root.Funcs(r.includeFuncs())
+	for _, tpl := range b.withExtends {
+		tpl.Funcs(r.includeFuncs())
+	}
+
+	r.setMu.Lock()
+	r.root, r.withExtends = b.root, b.withExtends
+	r.fileMap, r.layoutOf, r.baseTpl = b.fileMap, b.layoutOf, b.baseTpl
+	r.sources, r.srcNames = b.sources, b.srcNames
+	if remove {
+		delete(r.versions, name)
+	}
+	r.gen.Add(1)
+	r.setMu.Unlock()
+
+	for tplName := range deps {
+		r.InvalidateFragments(tplName)
+	}
+	return nil
+}
+
+// catchPanic run the fn, convert the panic to error.
+func catchPanic(fn func()) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			if er, ok := e.(error); ok {
+				err = er
+			} else {
+				err = fmt.Errorf("%v", e)
+			}
+		}
+	}()
+
+	fn()
+	return nil
+}
+
+// CompileError the errors on compile the templates. see Recompile()
+type CompileError struct {
+	Errors []error
+}
+
+// Error string
+func (e *CompileError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("easytpl: compile templates failed:")
+	for _, err := range e.Errors {
+		sb.WriteString("\n  ")
+		sb.WriteString(err.Error())
+	}
+	return sb.String()
+}
+
+// Unwrap the errors, for errors.Is and errors.As
+func (e *CompileError) Unwrap() []error { return e.Errors }
+
+// Recompile build a fresh templates set from the current options(ViewsDir and Loader),
+// then swap it to the renderer atomically. it is safe to call it on rendering.
+//
+// If any template failed to compile, returns *CompileError with all errors, the old templates keep serving.
+//
+// The templates added by LoadString, LoadStrings, LoadBytes, LoadFile, LoadFiles, LoadByGlob and Replace()
+// are compiled again from the loaded sources, unless the ViewsDir or Loader has the same name.
+// The renderer in a Registry is kept, so includeFrom still works.
+//
+// Usage:
+//
+//	// after deploy the new template files
+//	if err := r.Recompile(); err != nil {
+//		log.Println(err) // still serving the old templates
+//	}
+func (r *Renderer) Recompile() error {
+	r.requireInit("please call Init() before recompile templates")
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+
+	b := &Renderer{
+		Options: r.Options,
+		init:    true,
+		blockRe: r.blockRe,
+		extMap:  r.extMap,
+		tplDirs: r.tplDirs,
+	}
+	if r.EnableExtends {
+		b.baseTpl = make(map[string]string)
+		b.waitBase = make(map[string][]byte)
+		b.withExtends = make(map[string]*template.Template)
+	}
+
+	r.setMu.RLock()
+	keep := r.manualSources()
+	r.setMu.RUnlock()
+
+	if errs := b.compileAll(keep); len(errs) > 0 {
+		r.logDebug("easytpl: recompile templates failed", "errors", len(errs))
+		return &CompileError{Errors: errs}
+	}
+
+	// bind the funcs to the renderer
+	b.root.Funcs(r.includeFuncs())
+	for _, tpl := range b.withExtends {
+		tpl.Funcs(r.includeFuncs())
+	}
+
+	r.setMu.Lock()
+	oldNames := r.srcNames
+	r.root, r.withExtends = b.root, b.withExtends
+	r.fileMap, r.layoutOf, r.baseTpl = b.fileMap, b.layoutOf, b.baseTpl
+	r.sources, r.srcNames, r.versions = b.sources, b.srcNames, b.versions
+	r.gen.Add(1)
+	r.setMu.Unlock()
+
+	// invalidate the fragments of the old and new templates
+	for _, name := range append(slices.Clone(oldNames), b.srcNames...) {
+		r.InvalidateFragments(name)
+	}
+	r.misses.reset()
+	r.logDebug("easytpl: recompile templates is complete", "templates", len(b.srcNames))
+	return nil
+}
+
+// keptSource the loaded template source, which is not from the ViewsDir or the Loader.
+type keptSource struct {
+	name, path string
+	src        []byte
+}
+
+// manualSources collect the sources not loaded from the ViewsDir or the Loader. eg: by LoadString, Replace()
+//
+// NOTE: should be called with the setMu locked.
+func (r *Renderer) manualSources() (keep []keptSource) {
+	for _, name := range r.srcNames {
+		if _, ok := r.versions[name]; ok {
+			continue
+		}
+
+		path := r.fileMap[name]
+		if path != "" && r.inTplDirs(path) {
+			continue
+		}
+		keep = append(keep, keptSource{name: name, path: path, src: r.sources[name]})
+	}
+	return
+}
+
+// inTplDirs check the file path is in the ViewsDir.
+func (r *Renderer) inTplDirs(path string) bool {
+	for _, dir := range r.tplDirs {
+		rel, err := filepath.Rel(dir, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileAll compile the templates in the ViewsDir, the Loader and the kept sources, collect all errors.
+func (r *Renderer) compileAll(keep []keptSource) (errs []error) {
+	r.ensureRoot()
+	load := func(name string, fn func()) {
+		if err := catchPanic(fn); err != nil {
+			errs = append(errs, errorx.Ef("%s: %w", name, err))
+		}
+	}
+
+	for _, dir := range r.tplDirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			ext := filepath.Ext(path)
+			if info.IsDir() || !r.IsValidExt(ext) {
+				return nil
+			}
+
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+
+			name := rel[0 : len(rel)-len(ext)]
+			load(name, func() { r.loadFile(name, path, r.EnableExtends) })
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if r.Loader != nil {
+		names, err := r.Loader.Names()
+		if err != nil {
+			return append(errs, errorx.Ef("list the templates from loader failed: %w", err))
+		}
+
+		for _, name := range names {
+			bs, ver, err := r.readLoader(name)
+			if err != nil {
+				errs = append(errs, errorx.Ef("%s: %w", name, err))
+				continue
+			}
+
+			load(name, func() {
+				r.loadBytes(name, bs, r.EnableExtends)
+				r.setLoaded(name, ver)
+			})
+		}
+	}
+
+	for _, ks := range keep {
+		// the ViewsDir or Loader has the same name
+		if _, ok := r.sources[ks.name]; ok {
+			continue
+		}
+
+		load(ks.name, func() {
+			if ks.path != "" {
+				r.fileMap[ks.name] = ks.path
+			}
+			r.loadBytes(ks.name, ks.src, r.EnableExtends)
+		})
+	}
+
+	return append(errs, r.tryLoadWaitBase()...)
+}
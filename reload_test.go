@@ -0,0 +1,264 @@
+package easytpl_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/gookit/easytpl"
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+func TestRenderer_Replace(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewInited(easytpl.WithLayout("layout"))
+	r.LoadStrings(map[string]string{
+		"layout": `[{{ yield }}]`,
+		"home":   `home, {{ template "item" . }}, {{ include "footer" }}`,
+		"item":   `item: {{ . }}`,
+		"footer": `footer`,
+	})
+
+	is.NoErr(r.Render(bf, "home", "tom"))
+	is.Eq("[home, item: tom, footer]", bf.String())
+
+	// replace the template rendered inside others
+	is.NoErr(r.Replace("item", `new item: {{ . }}`))
+	is.NoErr(r.Replace("footer.tpl", `new footer`))
+	is.NoErr(r.Replace("layout", "{{ layout none }}\n<{{ yield }}>"))
+
+	bf.Reset()
+	is.NoErr(r.Render(bf, "home", "tom"))
+	is.Eq("&lt;home, new item: tom, new footer>", bf.String())
+
+	// parse error, keep the old templates
+	err := r.Replace("item", `{{ .Name `)
+	is.ErrSubMsg(err, `rebuild the templates for "item" failed`)
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "item", "tom"))
+	is.Eq("new item: tom", bf.String())
+
+	// remove
+	is.NoErr(r.Remove("footer"))
+	is.Nil(r.Template("footer"))
+	bf.Reset()
+	is.ErrSubMsg(r.Execute(bf, "home", "tom"), `the include template "footer" is not found`)
+	is.ErrSubMsg(r.Remove("footer"), `the template "footer" is not found`)
+
+	// add new one
+	is.NoErr(r.Replace("footer", `added footer`))
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "home", "tom"))
+	is.Eq("home, new item: tom, added footer", bf.String())
+}
+
+func TestRenderer_Remove_layout(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewInited(easytpl.WithLayout("layout"), func(r *easytpl.Renderer) {
+		r.LayoutParents = map[string]string{"admin/layout": "site/layout"}
+	})
+	r.LoadStrings(map[string]string{
+		"layout":       `[{{ yield }}]`,
+		"site/layout":  `<site>{{ yield }}</site>`,
+		"admin/layout": `<admin>{{ yield }}</admin>`,
+		"page/layout":  `<page>{{ yield }}</page>`,
+		"home":         `home`,
+		"about":        "{{ layout \"page/layout\" }}\nabout",
+	})
+
+	is.ErrSubMsg(r.Remove("layout"), `the template "layout" is used as layout by: Options.Layout`)
+	is.ErrSubMsg(r.Remove("site/layout"), `the template "site/layout" is used as layout by: admin/layout`)
+	is.ErrSubMsg(r.Remove("page/layout"), `the template "page/layout" is used as layout by: about`)
+	is.NotNil(r.Template("layout"))
+
+	// the page using it is removed
+	is.NoErr(r.Remove("about"))
+	is.NoErr(r.Remove("page/layout"))
+
+	// render with the not found layout
+	bf.Reset()
+	err := r.Render(bf, "home", nil, "page/layout")
+	is.ErrSubMsg(err, `the layout template "page/layout" is not found, want render: home`)
+}
+
+func TestRenderer_Replace_extends(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewExtends(easytpl.DisableLayout)
+	r.LoadStrings(map[string]string{
+		"base":  `{{ block "body" . }}default{{ end }}|{{ block "footer" . }}footer{{ end }}`,
+		"page":  "{{ extends \"base\" }}\n{{ define \"footer\" }}page footer{{ end }}",
+		"home":  "{{ extends \"page\" }}\n{{ define \"body\" }}hi {{.}}{{ end }}",
+		"other": "{{ extends \"base\" }}\n{{ define \"body\" }}other{{ end }}",
+	})
+
+	is.NoErr(r.Execute(bf, "home", "tom"))
+	is.Eq("hi tom|page footer", bf.String())
+
+	// the extends dependents are rebuilt
+	is.NoErr(r.Replace("base", `{{ block "body" . }}default{{ end }}+{{ block "footer" . }}footer{{ end }}`))
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "home", "tom"))
+	is.Eq("hi tom+page footer", bf.String())
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "other", nil))
+	is.Eq("other+footer", bf.String())
+
+	// replace the middle one
+	is.NoErr(r.Replace("page", "{{ extends \"base\" }}\n{{ define \"footer\" }}new footer{{ end }}"))
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "home", "tom"))
+	is.Eq("hi tom+new footer", bf.String())
+
+	// the extends templates call the replaced template
+	r.LoadString("partial", "old-partial")
+	is.NoErr(r.Replace("other", "{{ extends \"base\" }}\n{{ define \"body\" }}{{ template \"partial\" }}{{ end }}"))
+	is.NoErr(r.Replace("partial", "new-partial"))
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "other", nil))
+	is.Eq("new-partial+footer", bf.String())
+
+	// cannot remove the extended base
+	is.ErrSubMsg(r.Remove("base"), `the template "base" is extended by: home, other, page`)
+	is.NotNil(r.Template("base"))
+
+	is.NoErr(r.Remove("home"))
+	is.Nil(r.Template("home"))
+}
+
+func TestRenderer_Reload(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	file := filepath.Join(t.TempDir(), "hello.tpl")
+	is.NoErr(os.WriteFile(file, []byte(`hello {{ . }}`), 0644))
+
+	r := easytpl.NewInited()
+	r.LoadFile("hello", file)
+	r.LoadString("str", `str`)
+
+	is.NoErr(os.WriteFile(file, []byte(`hi {{ . }}`), 0644))
+	is.NoErr(r.Reload("hello.tpl"))
+	is.NoErr(r.Execute(bf, "hello", "tom"))
+	is.Eq("hi tom", bf.String())
+	is.Eq(file, r.TemplateFiles()["hello"])
+
+	is.ErrSubMsg(r.Reload("str"), `the template "str" is not loaded from file`)
+}
+
+func TestRenderer_Replace_concurrent(t *testing.T) {
+	r := easytpl.NewInited(easytpl.WithLayout("layout"))
+	r.LoadStrings(map[string]string{
+		"layout": `[{{ yield }}]`,
+		"home":   `home {{ include "part" }}`,
+		"part":   `part`,
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				bf := new(bytes.Buffer)
+				assert.NoErr(t, r.Render(bf, "home", nil))
+				assert.StrContains(t, bf.String(), "part")
+			}
+		}()
+	}
+
+	for j := 0; j < 20; j++ {
+		assert.NoErr(t, r.Replace("part", `new part`))
+	}
+	wg.Wait()
+}
+
+func TestRenderer_Recompile(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	dir := t.TempDir()
+	write := func(name, text string) {
+		is.NoErr(os.WriteFile(filepath.Join(dir, name), []byte(text), 0644))
+	}
+	write("layout.tpl", `[{{ yield }}]`)
+	write("home.tpl", `home {{ . }}`)
+
+	l := easytpl.NewMemoryLoader(map[string]string{"db/page": `db page`})
+	r := easytpl.NewInited(easytpl.WithTplDirs(dir), easytpl.WithLayout("layout"), easytpl.WithLoader(l))
+	r.LoadString("str", `str`)
+	other := filepath.Join(t.TempDir(), "other.tpl")
+	is.NoErr(os.WriteFile(other, []byte(`other`), 0644))
+	r.LoadFile("other", other)
+
+	is.NoErr(r.Render(bf, "home", "tom"))
+	is.Eq("[home tom]", bf.String())
+
+	// all compile errors are returned, the old set keep serving
+	write("home.tpl", `home {{ .Name `)
+	write("about.tpl", `about {{ end }}`)
+	l.Set("db/page", `{{ if }}`)
+
+	err := r.Recompile()
+	is.Err(err)
+	cerr, ok := err.(*easytpl.CompileError)
+	is.True(ok)
+	is.Len(cerr.Errors, 3)
+	is.StrContains(err.Error(), "about: ")
+	is.StrContains(err.Error(), "home: ")
+	is.StrContains(err.Error(), "db/page: ")
+
+	bf.Reset()
+	is.NoErr(r.Render(bf, "home", "tom"))
+	is.Eq("[home tom]", bf.String())
+
+	// swap the new set
+	write("home.tpl", `new home {{ . }}`)
+	write("about.tpl", `about`)
+	l.Set("db/page", `new db page`)
+	is.NoErr(r.Recompile())
+
+	bf.Reset()
+	is.NoErr(r.Render(bf, "home", "tom"))
+	is.Eq("[new home tom]", bf.String())
+
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "db/page", nil))
+	is.Eq("new db page", bf.String())
+	is.NotNil(r.Template("about"))
+	is.Eq(filepath.Join(dir, "about.tpl"), r.TemplateFiles()["about"])
+
+	// the templates not from the ViewsDir or Loader are kept
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "str", nil))
+	is.Eq("str", bf.String())
+	is.NotNil(r.Template("other"))
+	is.Eq(other, r.TemplateFiles()["other"])
+
+	// the removed file is not kept
+	is.NoErr(os.Remove(filepath.Join(dir, "about.tpl")))
+	is.NoErr(r.Recompile())
+	is.Nil(r.Template("about"))
+
+	// the fragments of the old templates are invalidated
+	tpls := map[string]string{"nav": `nav1`, "menu": `{{ includeCached "nav" "key" 0 }}`}
+	r.LoadStrings(tpls)
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "menu", nil))
+	is.Eq("nav1", bf.String())
+
+	is.NoErr(r.Recompile())
+	is.NotNil(r.Template("nav"))
+	tpls["nav"] = `nav2`
+	r.LoadStrings(tpls)
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "menu", nil))
+	is.Eq("nav2", bf.String())
+}
@@ -1 +1,254 @@
-package easytpl
+package easytpl_test
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+
+	"github.com/gookit/easytpl"
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+func TestRenderer_nestedLayouts(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewInited()
+	r.LoadStrings(map[string]string{
+		"site/layout":  `<site>{{ yield }}</site>`,
+		"admin/layout": "{{ layout \"site/layout\" }}\n<admin>{{ yield }}</admin>",
+		"admin/home":   `home {{ . }}`,
+	})
+
+	is.NoErr(r.Render(bf, "admin/home", "tom", "admin/layout"))
+	is.Eq("<site><admin>home tom</admin></site>", bf.String())
+
+	// the single layout
+	bf.Reset()
+	is.NoErr(r.Render(bf, "admin/home", "tom", "site/layout"))
+	is.Eq("<site>home tom</site>", bf.String())
+
+	// override by Options.LayoutParents
+	r.LayoutParents = map[string]string{"admin/layout": ""}
+	bf.Reset()
+	is.NoErr(r.Render(bf, "admin/home", "tom", "admin/layout"))
+	is.Eq("<admin>home tom</admin>", bf.String())
+
+	// cycle
+	r.LayoutParents = map[string]string{"site/layout": "admin/layout"}
+	err := r.Render(bf, "admin/home", "tom", "admin/layout")
+	is.ErrSubMsg(err, "layout cycle detected: admin/layout -> site/layout -> admin/layout")
+
+	// parent not found
+	r.LayoutParents = map[string]string{"site/layout": "not-exist"}
+	err = r.Render(bf, "admin/home", "tom", "admin/layout")
+	is.ErrSubMsg(err, `the parent layout "not-exist" of "site/layout" is not found`)
+}
+
+func TestRenderer_layoutDirective(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewInited(easytpl.WithLayout("site/layout"))
+	r.LoadStrings(map[string]string{
+		"site/layout":  `<site>{{ yield }}</site>`,
+		"admin/layout": `<admin>{{ yield }}</admin>`,
+		"home":         `home {{ . }}`,
+		"admin/home":   "\n{{ layout \"admin/layout\" }}\nadmin {{ . }}",
+		"api/data":     "{{- layout none -}}\n{\"name\": \"{{ . }}\"}",
+	})
+
+	tests := []struct {
+		page   string
+		layout []string
+		want   string
+	}{
+		{"home", nil, "<site>home tom</site>"},
+		{"admin/home", nil, "<admin>admin tom</admin>"},
+		{"api/data", nil, `{"name": "tom"}`},
+		// explicit layout argument wins
+		{"admin/home", []string{"site/layout"}, "<site>admin tom</site>"},
+		{"admin/home", []string{""}, "admin tom"},
+		{"api/data", []string{"admin/layout"}, `<admin>{"name": "tom"}</admin>`},
+	}
+
+	for _, tt := range tests {
+		bf.Reset()
+		is.NoErr(r.Render(bf, tt.page, "tom", tt.layout...))
+		is.Eq(tt.want, bf.String(), tt.page)
+	}
+
+	// partial will not apply the layout
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "admin/home", "tom"))
+	is.Eq("admin tom", bf.String())
+}
+
+func TestRenderer_layoutDirective_extends(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewExtends()
+	r.LoadStrings(map[string]string{
+		"lay":   `L({{ yield }})`,
+		"base":  `[{{ block "body" . }}B{{ end }}]`,
+		"page1": "{{ layout \"lay\" }}\n{{ extends \"base\" }}\n{{ define \"body\" }}P1{{ end }}",
+		"page2": "{{ extends \"base\" }}\n{{ layout \"lay\" }}\n{{ define \"body\" }}P2{{ end }}",
+	})
+
+	// the layout directive can be before or after the extends directive
+	tests := map[string]string{"page1": "L([P1])", "page2": "L([P2])"}
+	for name, want := range tests {
+		bf.Reset()
+		is.NoErr(r.Render(bf, name, nil))
+		is.Eq(want, bf.String(), name)
+	}
+}
+
+func TestRenderer_stacks(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewInited()
+	r.LoadStrings(map[string]string{
+		"layout": `<head>{{ stack "styles" }}</head><body>{{ yield }}{{ stack "scripts" }}</body>`,
+		"widget": `{{ once "jquery" }}{{ push "scripts" }}<script src="/jquery.js"></script>{{ end }}{{ end }}
+{{- push "scripts" }}<script>init({{ . }})</script>{{ end -}}
+<div>{{ . }}</div>`,
+		"home": `{{ prepend "scripts" }}<script src="/app.js"></script>{{ end -}}
+{{ push "styles" }}<link href="/home.css">{{ end -}}
+{{ range .List }}{{ include "widget" . }}{{ end }}`,
+	})
+
+	is.NoErr(r.Render(bf, "home", map[string]any{"List": []string{"a", "b"}}, "layout"))
+	is.Eq(`<head><link href="/home.css"></head><body><div>a</div><div>b</div>`+
+		`<script src="/app.js"></script><script src="/jquery.js"></script>`+
+		`<script>init("a")</script><script>init("b")</script></body>`, bf.String())
+
+	// stacks are isolated per render
+	bf.Reset()
+	is.NoErr(r.Render(bf, "home", map[string]any{"List": []string{"c"}}, "layout"))
+	is.StrContains(bf.String(), `<script src="/jquery.js"></script><script>init("c")</script>`)
+	is.NotContains(bf.String(), `init("a")`)
+
+	// push on the nested layouts
+	r.LoadString("admin", "{{ layout \"layout\" }}\n{{ push \"styles\" }}<link href=\"/admin.css\">{{ end }}[{{ yield }}]")
+	bf.Reset()
+	is.NoErr(r.Render(bf, "home", map[string]any{"List": []string{"d"}}, "admin"))
+	is.StrContains(bf.String(), `<head><link href="/home.css"><link href="/admin.css"></head><body>[<div>d</div>]`)
+
+	// page cannot call yield
+	r.LoadString("bad", `{{ yield }}`)
+	is.ErrSubMsg(r.Render(bf, "bad", nil, "layout"), "yield called with no layout defined")
+}
+
+func TestRenderer_stacks_customFunc(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	// the block funcs overridden by FuncMap are not rewritten
+	r := easytpl.NewInited(func(r *easytpl.Renderer) {
+		r.AddFunc("push", func(s string) string { return "push:" + s })
+	})
+	r.LoadString("home", `{{ push "msg" }}, {{ if push "a" }}yes{{ end }}, {{ once "k" }}once{{ end }}{{ once "k" }}twice{{ end }}`)
+
+	is.NoErr(r.Execute(bf, "home", nil))
+	is.Eq("push:msg, yes, once", bf.String())
+
+	bf.Reset()
+	is.NoErr(r.String(bf, `{{ push "str" }}`, nil))
+	is.Eq("push:str", bf.String())
+}
+
+func TestRenderer_customFuncs(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	// the funcs in FuncMap take precedence of the render funcs
+	r := easytpl.NewInited(func(r *easytpl.Renderer) {
+		r.AddFuncMap(template.FuncMap{
+			"get":     func(key string) string { return "custom-" + key },
+			"include": func(name string) string { return "include-" + name },
+		})
+	})
+	r.LoadString("home", `{{ set "x" "val" }}{{ get "x" }}, {{ include "part" }}`)
+
+	is.NoErr(r.Execute(bf, "home", nil))
+	is.Eq("custom-x, include-part", bf.String())
+	bf.Reset()
+	is.NoErr(r.Template("home").Execute(bf, nil))
+	is.Eq("custom-x, include-part", bf.String())
+}
+
+func TestRenderer_sections(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewInited(easytpl.WithLayout("layout"))
+	r.LoadStrings(map[string]string{
+		"layout": `<title>{{ yield "title" "Site" }}</title>
+{{- if hasSection "sidebar" }}<aside>{{ yield "sidebar" }}</aside>{{ end -}}
+<main>{{ yield }}</main>`,
+		"home": `{{ section "title" }}Home - {{ .Name }}{{ end -}}
+{{ section "sidebar" }}<ul><li>{{ .Name }}</li></ul>{{ end -}}
+home`,
+		"about": `about`,
+	})
+
+	is.NoErr(r.Render(bf, "home", map[string]string{"Name": "tom"}))
+	is.Eq(`<title>Home - tom</title><aside><ul><li>tom</li></ul></aside><main>home</main>`, bf.String())
+
+	// sections are scoped to the rendering page
+	bf.Reset()
+	is.NoErr(r.Render(bf, "about", nil))
+	is.Eq(`<title>Site</title><main>about</main>`, bf.String())
+
+	// the page section overrides the layout section
+	r.LoadString("admin", "{{ layout \"layout\" }}\n{{ section \"title\" }}Admin{{ end }}{{ section \"sidebar\" }}menu{{ end }}[{{ yield }}]")
+	bf.Reset()
+	is.NoErr(r.Render(bf, "home", map[string]string{"Name": "tom"}, "admin"))
+	is.Eq(`<title>Home - tom</title><aside><ul><li>tom</li></ul></aside><main>[home]</main>`, bf.String())
+	bf.Reset()
+	is.NoErr(r.Render(bf, "about", nil, "admin"))
+	is.Eq(`<title>Admin</title><aside>menu</aside><main>[about]</main>`, bf.String())
+
+	// the default value is escaped
+	r.LoadString("title", `<h1>{{ yield "title" .Title }}</h1>`)
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "title", map[string]any{"Title": "<script>alert(1)</script>"}))
+	is.Eq(`<h1>&lt;script&gt;alert(1)&lt;/script&gt;</h1>`, bf.String())
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "title", map[string]any{"Title": template.HTML("<b>Site</b>")}))
+	is.Eq(`<h1><b>Site</b></h1>`, bf.String())
+
+	r.LoadString("bad", `{{ yield 1 }}`)
+	is.ErrSubMsg(r.Execute(bf, "bad", nil), "yield usage")
+}
+
+func TestRenderer_setGet(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewInited(easytpl.WithLayout("layout"))
+	r.LoadStrings(map[string]string{
+		"layout": `<title>{{ get "title" "Site" }}</title><body class="{{ get "bodyClass" }}">{{ yield }}</body>`,
+		"nav":    `{{ set "bodyClass" "has-nav" }}nav`,
+		"orders": `{{ set "title" (printf "Orders(%d)" .) }}{{ include "nav" }} orders`,
+		"home":   "home",
+	})
+
+	is.NoErr(r.Render(bf, "orders", 3))
+	is.Eq(`<title>Orders(3)</title><body class="has-nav">nav orders</body>`, bf.String())
+
+	// isolated per render, without load templates between the renders
+	bf.Reset()
+	is.NoErr(r.Render(bf, "home", nil))
+	is.Eq(`<title>Site</title><body class="">home</body>`, bf.String())
+
+	bf.Reset()
+	is.NoErr(r.Render(bf, "orders", 5))
+	is.Eq(`<title>Orders(5)</title><body class="has-nav">nav orders</body>`, bf.String())
+	bf.Reset()
+	is.NoErr(r.Render(bf, "home", nil))
+	is.Eq(`<title>Site</title><body class="">home</body>`, bf.String())
+}
@@ -2,14 +2,19 @@ package easytpl
 
 import (
 	"bytes"
-	"fmt"
 	"html/template"
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template/parse"
 
 	"github.com/gookit/easytpl/tplfunc"
+	"github.com/gookit/goutil/errorx"
 	"github.com/gookit/goutil/maputil"
 	"github.com/gookit/goutil/x/basefn"
 )
@@ -20,6 +25,39 @@ type Renderer struct {
 	bufPool *bufferPool
 	// mark renderer is initialized
 	init bool
+	// gen the generation of loaded templates, will increase on load new templates.
+	gen atomic.Uint64
+	// setPool the pool of cloned templates set for execute. see execSet
+	setPool sync.Pool
+	// versions of the cached fragments. see includeCached
+	fragVers fragmentVers
+	// blockRe for rewrite the block funcs. see rewriteBlocks()
+	blockRe *regexp.Regexp
+	// lock for the Options.GlobalData. see SetGlobal()
+	globals globalData
+	// render middlewares and the composed render func. see Use()
+	middlewares []Middleware
+	renderFn    RenderFunc
+	// view composers. see Compose()
+	composers []composer
+	// render hooks. key is hook name. see On()
+	hooks map[string][]HookFunc
+	// collected render stats. see Stats()
+	stats statsCollector
+	// the registry of the renderer added to. for includeFrom
+	registry *Registry
+	// the not found names on lazy load from the Loader. see findTemplate()
+	misses lazyMisses
+	// setMu lock for swap the loaded templates set. see Replace(), Remove()
+	setMu sync.RWMutex
+	// reloadMu serialize the rebuilding of the templates set.
+	reloadMu sync.Mutex
+	// sources the loaded template contents, for rebuild the templates. format: {"tpl name": contents}
+	sources map[string][]byte
+	// srcNames the names of the sources, in load order.
+	srcNames []string
+	// versions of the templates loaded from the Options.Loader. format: {"tpl name": "version"}
+	versions map[string]string
 	// root It is the root template instance.
 	//
 	// It is like a map, contains all parsed templates.
@@ -39,6 +77,11 @@ type Renderer struct {
 	//
 	// NOTE: ext name with dot prefix. eg: {".tpl": 0, ".html": 0, ... ...}
 	extMap map[string]uint8
+	// the layout declared by directive on the template first line. eg: {{ layout "site/layout" }}
+	// for page it is the layout to render with, for layout it is the parent layout.
+	//
+	// format: {"tpl name": "layout name"}, the "none" is stored as empty string.
+	layoutOf map[string]string
 
 	// ------- feature on Options.EnableExtends is True -------
 
@@ -134,7 +177,7 @@ func (r *Renderer) Init() error {
 		return nil
 	}
 
-	r.debugf("begin initialize the renderer: init fields, add funcs ...")
+	r.logDebug("easytpl: begin initialize the renderer", "viewsDir", r.ViewsDir)
 	if len(r.ViewsDir) > 0 {
 		r.tplDirs = strings.Split(r.ViewsDir, ",")
 	}
@@ -143,12 +186,17 @@ func (r *Renderer) Init() error {
 	if len(r.ExtNames) == 0 {
 		r.ExtNames = []string{DefaultExt, DefaultExt1}
 	}
+	if r.FragmentCache == nil {
+		r.FragmentCache = NewMemoryCache(DefaultCacheSize)
+	}
 	if r.EnableExtends {
 		r.baseTpl = make(map[string]string)
 		r.waitBase = make(map[string][]byte)
 		r.withExtends = make(map[string]*template.Template)
 	}
 
+	r.blockRe = blockRegex(r.Delims, blockFuncNames)
+
 	// init ext map
 	r.extMap = make(map[string]uint8, len(r.ExtNames))
 	for _, ext := range r.ExtNames {
@@ -164,7 +212,7 @@ func (r *Renderer) Init() error {
 		return err
 	}
 
-	r.debugf("renderer initialize is complete, added template func: %d", len(r.FuncMap))
+	r.logDebug("easytpl: renderer initialize is complete", "funcs", len(r.FuncMap))
 	return nil
 }
 
@@ -190,7 +238,7 @@ func (r *Renderer) LoadByGlob(pattern string, baseDirs ...string) {
 	if len(baseDirs) == 1 {
 		baseDir = baseDirs[0]
 	}
-	r.debugf("load template files by glob: %s, baseDir: %s", pattern, baseDir)
+	r.logDebug("easytpl: load template files by glob", "pattern", pattern, "baseDir", baseDir)
 
 	for _, path := range paths {
 		ext := filepath.Ext(path)
@@ -242,7 +290,7 @@ func (r *Renderer) loadFile(tplName, filePath string, waitBase bool) {
 	panicErr(err)
 
 	r.fileMap[tplName] = filePath
-	r.debugf("load template file: %s, template name: %s", filePath, tplName)
+	r.logDebug("easytpl: load template file", "name", tplName, "path", filePath)
 	r.loadBytes(tplName, bs, waitBase)
 }
 
@@ -254,7 +302,7 @@ func (r *Renderer) loadFile(tplName, filePath string, waitBase bool) {
 //	// now, you can use "my-page" as a template name
 //	r.Partial(w, "my-page", "tom") // Result: "welcome tom"
 func (r *Renderer) LoadString(tplName, tplText string) {
-	r.debugf("load named template text, name is: %s", tplName)
+	r.logDebug("easytpl: load template text", "name", tplName)
 	r.loadBytes(tplName, []byte(tplText), false)
 }
 
@@ -262,7 +310,7 @@ func (r *Renderer) LoadString(tplName, tplText string) {
 // key is template name, value is template contents.
 func (r *Renderer) LoadStrings(sMap map[string]string) {
 	for name, tplText := range sMap {
-		r.debugf("load named template text, name is: %s", name)
+		r.logDebug("easytpl: load template text", "name", name)
 		r.loadBytes(name, []byte(tplText), r.EnableExtends)
 	}
 
@@ -272,12 +320,16 @@ func (r *Renderer) LoadStrings(sMap map[string]string) {
 
 // LoadBytes load named template bytes. will panic on error
 func (r *Renderer) LoadBytes(tplName string, tplText []byte) {
-	r.debugf("load named template bytes, name is: %s", tplName)
+	r.logDebug("easytpl: load template bytes", "name", tplName)
 	r.loadBytes(tplName, tplText, false)
 }
 
 func (r *Renderer) loadBytes(tplName string, bs []byte, waitBase bool) {
 	r.ensureRoot()
+	src := bs
+	// the layout directive can be on the first line, or the line after the extends directive.
+	delete(r.layoutOf, r.cleanExt(tplName))
+	bs, hasLayout := r.parseLayoutDirective(tplName, bs)
 
 	// parse the first line of the text, collect the base template name
 	if r.EnableExtends {
@@ -288,53 +340,111 @@ func (r *Renderer) loadBytes(tplName string, bs []byte, waitBase bool) {
 			baseName, ok := getExtendsTplName(bs[0:i], r.Delims)
 			if ok {
 				bs = bs[i+1:] // remove the first line
+				if !hasLayout {
+					bs, _ = r.parseLayoutDirective(tplName, bs)
+				}
+				bs = r.keepLines(src, bs)
 				r.baseTpl[tplName] = baseName
+				r.logDebug("easytpl: resolve the extends base template", "name", tplName, "base", baseName)
 
-				if base := r.Template(baseName); base != nil {
-					r.loadWithExtendsTpl(tplName, bs, base)
-				} else if waitBase {
+				// on load multi templates, delay load it after all loaded. then it can call the templates in the root.
+				if waitBase {
 					r.waitBase[tplName] = bs
+				} else if base := r.master(baseName); base != nil {
+					r.loadWithExtendsTpl(tplName, bs, base)
 				} else {
 					panicf("the base template %q is not found, want load: %s", baseName, tplName)
 				}
+				r.addSource(tplName, src)
 				return
 			}
 		}
 	}
 
+	text := r.rewriteBlocks(string(r.keepLines(src, bs)))
+	if r.Sandbox {
+		panicErr(r.checkTemplateCalls(tplName, text, nil))
+	}
+
 	// create new template in the root, will inherit delimiters and all func map
-	template.Must(r.root.New(tplName).Parse(string(bs)))
+	template.Must(r.root.New(tplName).Parse(text))
+	r.addSource(tplName, src)
+	r.gen.Add(1)
 }
 
-func (r Renderer) loadWaitBase() {
+func (r *Renderer) loadWaitBase() {
 	if !r.EnableExtends || len(r.waitBase) == 0 {
 		return
 	}
 
-	for name, bs := range r.waitBase {
-		baseName := r.baseTpl[name]
-		if base := r.Template(baseName); base != nil {
-			r.loadWithExtendsTpl(name, bs, base)
-		} else {
-			panicf("the extends base template %q is not found, want load: %s", baseName, name)
+	if errs := r.tryLoadWaitBase(); len(errs) > 0 {
+		panicErr(errs[0])
+	}
+}
+
+// tryLoadWaitBase load the wait base templates, returns all errors.
+func (r *Renderer) tryLoadWaitBase() (errs []error) {
+	// the base template maybe also is waiting, so load until no progress.
+	for len(r.waitBase) > 0 {
+		var loaded bool
+		for name, bs := range r.waitBase {
+			base := r.master(r.baseTpl[name])
+			if base == nil {
+				continue
+			}
+
+			if err := catchPanic(func() { r.loadWithExtendsTpl(name, bs, base) }); err != nil {
+				errs = append(errs, err)
+			}
+			delete(r.waitBase, name)
+			loaded = true
+		}
+
+		if !loaded {
+			names := maputil.Keys(r.waitBase)
+			sort.Strings(names)
+			for _, name := range names {
+				errs = append(errs, errorx.Ef("the extends base template %q is not found, want load: %s", r.baseTpl[name], name))
+			}
+			break
 		}
 	}
 
 	// clear caches
 	r.waitBase = nil
+	return errs
 }
 
-func (r Renderer) loadWithExtendsTpl(name string, bs []byte, base *template.Template) {
+func (r *Renderer) loadWithExtendsTpl(name string, bs []byte, base *template.Template) {
+	text := r.rewriteBlocks(string(bs))
+	if r.Sandbox {
+		// can call the templates defined in the base. eg: override the blocks
+		panicErr(r.checkTemplateCalls(name, text, func(name string) bool { return base.Lookup(name) != nil }))
+	}
+
 	// NOTICE: must use a clone for base template
-	tpl := template.Must(template.Must(base.Clone()).Parse(string(bs)))
+	tpl := template.Must(base.Clone())
+	trees := make(map[string]*parse.Tree)
+	for _, t := range tpl.Templates() {
+		trees[t.Name()] = t.Tree
+	}
+
+	template.Must(tpl.Parse(text))
+	// the templates defined in the text, set parse name for locate the source. see DataRefs()
+	for _, t := range tpl.Templates() {
+		if t.Tree != nil && t.Tree != trees[t.Name()] {
+			t.Tree.ParseName = name
+		}
+	}
 	// update name
-	tpl.Tree.Name, tpl.Tree.ParseName = name, name
+	tpl.Tree.Name = name
 
 	// TIP: TODO add to root template cannot get want result.
 	// basefn.MustIgnore(r.root.AddParseTree(name, tpl.Tree))
 
 	// NEW: use a map to storage all contains "extends" statement tpl instance
 	r.withExtends[name] = tpl
+	r.gen.Add(1)
 }
 
 /*************************************************************
@@ -354,15 +464,44 @@ func (r *Renderer) ensureRoot() {
 	}
 }
 
+// keepLines replace the removed directive lines by a comment with same newlines,
+// so the line numbers of the template are not changed. the rest must be a suffix of the src.
+//
+//	{{ extends "base" }}\n{{ define "body" }} -> {{/*\n*/}}{{ define "body" }}
+func (r *Renderer) keepLines(src, rest []byte) []byte {
+	n := bytes.Count(src[:len(src)-len(rest)], []byte{'\n'})
+	if n == 0 {
+		return rest
+	}
+
+	bs := make([]byte, 0, len(rest)+n+8)
+	bs = append(bs, r.Delims.Left+"/*"+strings.Repeat("\n", n)+"*/"+r.Delims.Right...)
+	return append(bs, rest...)
+}
+
+// addSource record the template contents, for rebuild the templates.
+func (r *Renderer) addSource(tplName string, bs []byte) {
+	if r.sources == nil {
+		r.sources = make(map[string][]byte)
+	}
+	if _, ok := r.sources[tplName]; !ok {
+		r.srcNames = append(r.srcNames, tplName)
+	}
+	r.sources[tplName] = bs
+}
+
 // newTemplate create a new template instance and set delimiters and func map
 func (r *Renderer) newTemplate(name string) *template.Template {
+	stdFuncs := tplfunc.StdFuncMap()
+	if r.Sandbox {
+		stdFuncs = tplfunc.SafeFuncMap()
+	}
+
 	tpl := template.New(name).
 		Delims(r.Delims.Left, r.Delims.Right).
 		Funcs(builtInFuncMap).
-		Funcs(tplfunc.StdFuncMap()).
-		Funcs(template.FuncMap{
-			"include": r.handleInclude,
-		})
+		Funcs(stdFuncs).
+		Funcs(r.includeFuncs())
 
 	if len(r.FuncMap) > 0 {
 		tpl.Funcs(r.FuncMap)
@@ -379,12 +518,18 @@ func (r *Renderer) compileTemplates() error {
 		}
 	}
 
+	if r.Loader != nil {
+		if err := r.compileLoader(); err != nil {
+			return err
+		}
+	}
+
 	r.loadWaitBase()
 	return nil
 }
 
 func (r *Renderer) compileInDir(dir string) error {
-	r.debugf("will compile templates in the dir: %s", dir)
+	r.logDebug("easytpl: compile templates in the dir", "dir", dir)
 
 	// Walk the supplied directory and compile any files that match our extension list.
 	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
@@ -415,43 +560,15 @@ func (r *Renderer) compileInDir(dir string) error {
 	})
 }
 
-// name the template name
-func (r *Renderer) addLayoutFuncs(layout, name string, data any) {
-	tpl := r.Template(layout)
-	if tpl == nil {
-		panicf("the layout template %q is not found, want render: %s", layout, name)
-	}
-
-	// includeHandler := func(tplName string) (template.HTML, error) {
-	// 	if r.root.Lookup(tplName) != nil {
-	// 		str, err := r.executeTemplate(tplName, data)
-	// 		// Return safe HTML here since we are rendering our own template.
-	// 		return template.HTML(str), err
-	// 	}
-	//
-	// 	return "", nil
-	// 	}
-
-	r.debugf("add funcs[yield, partial] to layout template: %s, target template: %s", layout, name)
-	//goland:noinspection ALL
-	tpl.Funcs(template.FuncMap{
-		"yield": func() (template.HTML, error) {
-			bs, err := r.executeByName(name, data)
-			return template.HTML(bs), err
-		},
-		// Will add data to included template
-		// "include": includeHandler,
-		// "partial": includeHandler,
-	})
-}
-
 /*************************************************************
  * Helper methods
  *************************************************************/
 
 // Templates returns loaded template instances, including ROOT itself.
+//
+// NOTE: the returned templates are cloned, see Root()
 func (r *Renderer) Templates() []*template.Template {
-	return r.root.Templates()
+	return r.Root().Templates()
 }
 
 // TemplateFiles returns loaded template files
@@ -459,6 +576,13 @@ func (r *Renderer) TemplateFiles() map[string]string {
 	return r.fileMap
 }
 
+// fileOf get the loaded file path of the template, empty on not loaded from file.
+func (r *Renderer) fileOf(tplName string) string {
+	r.setMu.RLock()
+	defer r.setMu.RUnlock()
+	return r.fileMap[r.cleanExt(tplName)]
+}
+
 var nameRpl = strings.NewReplacer(":", ":\n", ",", "\n")
 
 // TemplateNames returns loaded template names.
@@ -479,11 +603,32 @@ func (r *Renderer) TemplateNames(pretty ...bool) string {
 }
 
 // Root returns root template instance
-func (r *Renderer) Root() *template.Template { return r.root }
+//
+// NOTE: the returned template is a clone of the loaded templates, execute or modify it will not affect the renderer.
+func (r *Renderer) Root() *template.Template {
+	r.setMu.RLock()
+	defer r.setMu.RUnlock()
+	if r.root == nil {
+		return nil
+	}
+	return template.Must(r.root.Clone())
+}
 
 // Template get template instance by name, if not exists, return nil
+//
+// NOTE: the returned template is a clone of the loaded template, execute or modify it will not affect the renderer.
 func (r *Renderer) Template(name string) *template.Template {
+	if tpl := r.master(name); tpl != nil {
+		return template.Must(tpl.Clone())
+	}
+	return nil
+}
+
+// master get the loaded template by name. it is used as master for clone on render, must not be executed.
+func (r *Renderer) master(name string) *template.Template {
 	noExt := r.cleanExt(name)
+	r.setMu.RLock()
+	defer r.setMu.RUnlock()
 
 	// find with extends template
 	if len(r.withExtends) > 0 {
@@ -529,7 +674,9 @@ func (r *Renderer) cleanExt(name string) string {
 	return name
 }
 
-func (r *Renderer) getLayoutName(tplNames []string) string {
+// getLayoutName get the layout for render the page.
+// priority: explicit layout argument > layout directive in the page > Options.Layout
+func (r *Renderer) getLayoutName(page string, tplNames []string) string {
 	var layout string
 	disableLayout := r.DisableLayout
 
@@ -538,6 +685,9 @@ func (r *Renderer) getLayoutName(tplNames []string) string {
 		if layout == "" {
 			disableLayout = true
 		}
+	} else if name, ok := r.layoutDirective(page); ok {
+		// "none" is stored as empty string
+		layout = name
 	} else {
 		layout = r.Layout
 	}
@@ -560,8 +710,9 @@ func (r *Renderer) cannotInit(format string, args ...any) {
 	}
 }
 
-func (r *Renderer) debugf(format string, args ...any) {
-	if r.Debug {
-		fmt.Printf("easytpl: [DEBUG] "+format+"\n", args...)
+// logDebug log the debug event, only on Debug is enabled and Logger is set.
+func (r *Renderer) logDebug(msg string, args ...any) {
+	if r.Debug && r.Logger != nil {
+		r.Logger.Debug(msg, args...)
 	}
 }
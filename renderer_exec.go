@@ -1,7 +1,7 @@
 package easytpl
 
 import (
-	"fmt"
+	"context"
 	"html/template"
 	"io"
 
@@ -26,14 +26,52 @@ import (
 //
 //	// will disable apply layout render
 //	renderer.Render(http.ResponseWriter, "user/login", data, "")
+//
+// The page template can select the layout by directive on the first line, explicit layout argument will win.
+//
+//	{{ layout "admin/layout" }}
+//	{{ layout none }}
 func (r *Renderer) Render(w io.Writer, tplName string, v any, layout ...string) error {
+	return r.RenderContext(context.Background(), w, tplName, v, layout...)
+}
+
+// RenderContext render a template name/file with layout, will honour the context cancellation and deadline.
+//
+// The execute limits can be overridden by ContextWithLimits()
+func (r *Renderer) RenderContext(ctx context.Context, w io.Writer, tplName string, v any, layout ...string) error {
+	return r.renderFunc()(ctx, w, tplName, v, layout...)
+}
+
+// render the template with layout, it is the innermost RenderFunc of the middlewares.
+func (r *Renderer) render(ctx context.Context, w io.Writer, tplName string, v any, layout ...string) error {
+	r.requireInit("please call Init() before execute template")
+
+	st, cancel := r.newState(ctx)
+	defer cancel()
+
+	// lazy load the page template by the Loader
+	if _, err := r.findTemplate(tplName); err != nil {
+		return err
+	}
+
 	// Apply layout render
-	if layoutName := r.getLayoutName(layout); layoutName != "" {
-		r.addLayoutFuncs(layoutName, tplName, v)
-		tplName = layoutName
+	var layouts []string
+	if layoutName := r.getLayoutName(tplName, layout); layoutName != "" {
+		tpl, err := r.findTemplate(layoutName)
+		if err != nil {
+			return err
+		}
+		if tpl == nil {
+			return errorx.Ef("easytpl: the layout template %q is not found, want render: %s", layoutName, tplName)
+		}
+
+		if layouts, err = r.layoutChain(layoutName); err != nil {
+			return err
+		}
+		r.logDebug("easytpl: select the layout for render", "name", tplName, "layouts", layouts)
 	}
 
-	return r.Execute(w, tplName, v)
+	return r.executeWith(st, w, tplName, layouts, v)
 }
 
 // Partial is alias of the Execute()
@@ -42,68 +80,138 @@ func (r *Renderer) Partial(w io.Writer, tplName string, v any) error {
 }
 
 // Execute render partial, will not render layout file
-func (r *Renderer) Execute(w io.Writer, tplName string, v any) (err error) {
-	r.requireInit("please call Init() before execute template")
+func (r *Renderer) Execute(w io.Writer, tplName string, v any) error {
+	return r.ExecuteContext(context.Background(), w, tplName, v)
+}
 
-	// render template by name
-	bs, err := r.executeByName(tplName, v)
-	if err == nil {
-		_, err = w.Write(bs)
-	}
-	return err
+// ExecuteContext render partial, will not render layout file. will honour the context cancellation and deadline.
+func (r *Renderer) ExecuteContext(ctx context.Context, w io.Writer, tplName string, v any) error {
+	// use empty layout for disable layout render
+	return r.renderFunc()(ctx, w, tplName, v, "")
 }
 
 // String render a template string with data
 func (r *Renderer) String(w io.Writer, tplText string, v any) error {
-	// must create a new tmp template instance
-	t := r.newTemplate("string-tpl")
-	return template.Must(t.Parse(tplText)).Execute(w, v)
-}
+	st, cancel := r.newState(context.Background())
+	defer cancel()
 
-// execute data render by template name
-func (r *Renderer) executeByName(name string, v any) ([]byte, error) {
-	tpl := r.Template(name)
-	if tpl == nil {
-		return nil, fmt.Errorf("easytpl: execute template %q is not found", name)
+	es, err := r.getSet(st)
+	if err != nil {
+		return err
 	}
-	return r.executeTemplate(tpl, v)
-}
+	defer r.putSet(es)
 
-// execute data render by template instance
-func (r *Renderer) executeTemplate(tpl *template.Template, v any) ([]byte, error) {
-	// get a buffer from the pool to write to.
-	buf := r.bufPool.get()
-	// name := tpl.Name()
-	name := tpl.Tree.Name
-
-	tpl.Funcs(template.FuncMap{
-		// get current template name
-		"current_tpl": func() string {
-			return name
-		},
+	// must create a new tmp template instance
+	t := r.newTemplate("string-tpl").Funcs(es.funcs())
+	template.Must(t.Parse(r.rewriteBlocks(tplText)))
+	prepareTree(t.Tree, r.blockFuncs())
+
+	v = r.withGlobal(v)
+	return r.track(&RenderEvent{Ctx: st.ctx, Name: t.Name()}, func() (int, error) {
+		cw := &countWriter{w: w}
+		if !r.Minify {
+			err := es.executeWriter(cw, t, v)
+			return cw.n, err
+		}
+
+		// streaming minify the output
+		mw := NewMinifyWriter(cw)
+		if err := es.executeWriter(mw, t, v); err != nil {
+			return cw.n, err
+		}
+		err := mw.Close()
+		return cw.n, err
 	})
+}
 
-	r.debugf("execute the template %q, override set func: current_tpl", name)
-	err := tpl.Execute(buf, v)
-	bts := buf.Bytes()
+// execute the page template with the render state, write result to w on success.
+//
+// layouts is the layout chain from inner to outer. if not empty, will do a two-phase render:
+// render the page first, then render the layouts from inner to outer, each {{ yield }}
+// output the rendered inner level content. so the contents pushed by the page can be
+// output by {{ stack "name" }} in the layouts.
+func (r *Renderer) executeWith(st *renderState, w io.Writer, page string, layouts []string, v any) error {
+	es, err := r.getSet(st)
+	if err != nil {
+		return err
+	}
+	defer r.putSet(es)
+
+	ev := &RenderEvent{Ctx: st.ctx, Name: page}
+	if len(layouts) > 0 {
+		ev.Layout = layouts[0]
+	}
 
-	// release buffer to pool
-	r.bufPool.put(buf)
-	return bts, err
+	v = r.withGlobal(v)
+
+	return r.track(ev, func() (int, error) {
+		// get a buffer from the pool to write to.
+		buf := r.bufPool.get()
+		defer r.bufPool.put(buf)
+
+		if err := es.execute(buf, page, v); err != nil {
+			return 0, err
+		}
+
+		for _, layout := range layouts {
+			st.inLayout, st.content = true, template.HTML(buf.String())
+			buf.Reset()
+			if err := es.execute(buf, layout, v); err != nil {
+				return 0, err
+			}
+		}
+
+		if r.Minify {
+			cw := &countWriter{w: w}
+			mw := NewMinifyWriter(cw)
+			if _, err := mw.Write(buf.Bytes()); err != nil {
+				return cw.n, err
+			}
+			err := mw.Close()
+			return cw.n, err
+		}
+		return w.Write(buf.Bytes())
+	})
 }
 
-func (r *Renderer) handleInclude(tplName string, data ...any) (template.HTML, error) {
-	tpl := r.Template(tplName)
-	if tpl == nil {
-		return "", errorx.Ef("the include template %q is not found", tplName)
+// includeFuncs for execute the loaded template directly. eg: r.Template("name").Execute(w, data)
+//
+// NOTE: on Render/Execute, these funcs will be replaced by execSet.funcs()
+func (r *Renderer) includeFuncs() template.FuncMap {
+	fm := template.FuncMap{
+		"include": func(tplName string, data ...any) (template.HTML, error) {
+			return r.detached(func(es *execSet) (template.HTML, error) {
+				return es.include(tplName, data...)
+			})
+		},
+		"includeCached": func(tplName, key string, ttl any, data ...any) (template.HTML, error) {
+			return r.detached(func(es *execSet) (template.HTML, error) {
+				return es.includeCached(tplName, key, ttl, data...)
+			})
+		},
+		"includeFrom": func(name, tplName string, data ...any) (template.HTML, error) {
+			return r.detached(func(es *execSet) (template.HTML, error) {
+				return es.includeFrom(name, tplName, data...)
+			})
+		},
 	}
 
-	// do render template with data
-	var v any
-	if len(data) == 1 {
-		v = data[0]
+	// the funcs in Options.FuncMap take precedence
+	for name := range r.FuncMap {
+		delete(fm, name)
 	}
+	return fm
+}
 
-	bs, err := r.executeTemplate(tpl, v)
-	return template.HTML(bs), err
+// detached run fn with a new render state and exec set.
+func (r *Renderer) detached(fn func(es *execSet) (template.HTML, error)) (template.HTML, error) {
+	st, cancel := r.newState(context.Background())
+	defer cancel()
+
+	es, err := r.getSet(st)
+	if err != nil {
+		return "", err
+	}
+	defer r.putSet(es)
+	return fn(es)
 }
@@ -0,0 +1,420 @@
+package easytpl
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+
+	"github.com/gookit/goutil/errorx"
+)
+
+// renderState the state of each render call. it is isolated per render.
+type renderState struct {
+	ctx    context.Context
+	limits Limits
+	// current include/yield nesting depth
+	depth int
+	// total range loop iterations
+	iterations int
+	// executing template names stack. for the func current_tpl
+	names []string
+	// executing template writers stack. for capture the push/prepend blocks
+	writers []*limitWriter
+
+	// ------- for layout render -------
+
+	// inLayout mark is rendering the layout
+	inLayout bool
+	// content the rendered inner level content for {{ yield }}. eg: page or the child layout.
+	content template.HTML
+	// stacks the pushed contents. see {{ push "name" }}, {{ stack "name" }}
+	stacks map[string][]string
+	// onceKeys the used keys of {{ once "key" }}
+	onceKeys map[string]bool
+	// sections the rendered named sections. see {{ section "name" }}, {{ yield "name" }}
+	sections map[string]template.HTML
+	// vars the render scope variables. see {{ set "name" val }}, {{ get "name" }}
+	vars map[string]any
+	// recs the recorders of the rendering cached fragments. see includeCached
+	recs []*fragmentRec
+}
+
+func (r *Renderer) newState(ctx context.Context) (*renderState, context.CancelFunc) {
+	st := &renderState{limits: limitsFromContext(ctx, r.Limits)}
+	if st.limits.MaxDepth <= 0 {
+		st.limits.MaxDepth = DefaultMaxDepth
+	}
+
+	cancel := func() {}
+	if st.limits.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, st.limits.Timeout)
+	}
+
+	st.ctx = ctx
+	return st, cancel
+}
+
+// enter an include/yield level, check the depth limit and render context.
+func (st *renderState) enter() error {
+	if err := st.ctx.Err(); err != nil {
+		return err
+	}
+
+	st.depth++
+	if st.depth > st.limits.MaxDepth {
+		return &LimitError{Limit: "MaxDepth", Max: st.limits.MaxDepth}
+	}
+	return nil
+}
+
+func (st *renderState) leave() { st.depth-- }
+
+// tick on each range iteration, check the iterations limit and render context.
+func (st *renderState) tick() (bool, error) {
+	if err := st.ctx.Err(); err != nil {
+		return false, err
+	}
+
+	st.iterations++
+	if max := st.limits.MaxRangeIterations; max > 0 && st.iterations > max {
+		return false, &LimitError{Limit: "MaxRangeIterations", Max: max}
+	}
+	return false, nil
+}
+
+// set a render scope variable, returns empty string for output nothing.
+//
+// Usage:
+//
+//	{{ set "title" "Orders" }}
+func (st *renderState) set(name string, val any) string {
+	if s, ok := val.(string); ok {
+		st.apply(fragmentOp{Op: "set", Name: name, Val: s})
+		return ""
+	}
+
+	for _, rec := range st.recs {
+		rec.skip = fmt.Sprintf("set the var %q with %T value", name, val)
+	}
+	if st.vars == nil {
+		st.vars = make(map[string]any)
+	}
+	st.vars[name] = val
+	return ""
+}
+
+// get a render scope variable, will return the default value if not set.
+//
+// Usage:
+//
+//	{{ get "title" }}
+//	{{ get "title" "Default Title" }}
+func (st *renderState) get(name string, def ...any) any {
+	if val, ok := st.vars[name]; ok {
+		return val
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	return nil
+}
+
+func (st *renderState) currentName() string {
+	if len(st.names) > 0 {
+		return st.names[len(st.names)-1]
+	}
+	return ""
+}
+
+/*************************************************************
+ * template set for execute
+ *************************************************************/
+
+// execSet is a cloned templates set for execute.
+//
+// The loaded templates in the Renderer are never executed, each render will
+// take a cloned set from the pool. the template funcs of the set are bound to
+// it, so they can access the current render state.
+type execSet struct {
+	r *Renderer
+	// gen the templates generation on created.
+	gen  uint64
+	root *template.Template
+	// cloned with extends templates, or the templates loaded after the set created. lazy clone on use.
+	extends map[string]*template.Template
+	// st the current render state
+	st *renderState
+}
+
+// getSet get an exec set from the pool, create new one if pool is empty or outdated.
+func (r *Renderer) getSet(st *renderState) (*execSet, error) {
+	if v := r.setPool.Get(); v != nil {
+		if es := v.(*execSet); es.gen == r.gen.Load() {
+			es.st = st
+			return es, nil
+		}
+	}
+
+	// read the gen and clone the root together, the set maybe swapped at the same time.
+	r.setMu.RLock()
+	gen := r.gen.Load()
+	root, err := r.root.Clone()
+	r.setMu.RUnlock()
+
+	es := &execSet{r: r, gen: gen, st: st, extends: make(map[string]*template.Template)}
+	if err != nil {
+		return nil, errorx.Ef("easytpl: clone the templates for execute failed: %w", err)
+	}
+
+	es.root = es.prepare(root)
+	return es, nil
+}
+
+func (r *Renderer) putSet(es *execSet) {
+	es.st = nil
+	if es.gen == r.gen.Load() {
+		r.setPool.Put(es)
+	}
+}
+
+// prepare the cloned template: bind funcs, add range ticks.
+func (es *execSet) prepare(tpl *template.Template) *template.Template {
+	tpl.Funcs(es.funcs())
+	blockFuncs := es.r.blockFuncs()
+	for _, t := range tpl.Templates() {
+		if t.Tree != nil {
+			prepareTree(t.Tree, blockFuncs)
+		}
+	}
+	return tpl
+}
+
+// funcs returns the template funcs bound to the exec set.
+// the funcs overridden by Options.FuncMap are not included.
+func (es *execSet) funcs() template.FuncMap {
+	fm := template.FuncMap{
+		"include":       es.include,
+		"includeCached": es.includeCached,
+		"includeFrom":   es.includeFrom,
+		"yield":         es.yield,
+		// get current template name
+		"current_tpl": func() string {
+			return es.st.currentName()
+		},
+		tickFuncName: func() (bool, error) {
+			return es.st.tick()
+		},
+		// content stacks
+		"push": func(stack string) (bool, error) {
+			return es.st.startCapture(stack, capturePush)
+		},
+		"prepend": func(stack string) (bool, error) {
+			return es.st.startCapture(stack, capturePrepend)
+		},
+		// named sections
+		"section": func(name string) (bool, error) {
+			return es.st.startCapture(name, captureSection)
+		},
+		"hasSection": func(name string) bool {
+			_, ok := es.st.sections[name]
+			return ok
+		},
+		// render scope variables
+		"set": func(key string, val any) string {
+			return es.st.set(key, val)
+		},
+		"global": es.r.Global,
+		"get": func(key string, def ...any) any {
+			return es.st.get(key, def...)
+		},
+		"once": func(key string) bool {
+			return es.st.once(key)
+		},
+		"stack": func(name string) template.HTML {
+			return es.st.stack(name)
+		},
+		endCaptureFuncName: func() (bool, error) {
+			return es.st.endCapture()
+		},
+	}
+
+	// the funcs in Options.FuncMap take precedence, except the internal funcs
+	for name := range es.r.FuncMap {
+		if name != tickFuncName && name != endCaptureFuncName {
+			delete(fm, name)
+		}
+	}
+	return fm
+}
+
+// lookup the template by name, resolve rules same as Renderer.Template()
+func (es *execSet) lookup(name string) (*template.Template, error) {
+	r := es.r
+	noExt := r.cleanExt(name)
+
+	r.setMu.RLock()
+	withExtends := r.withExtends
+	r.setMu.RUnlock()
+
+	// find with extends template
+	if len(withExtends) > 0 {
+		for _, key := range []string{noExt, name} {
+			if tpl, ok := es.extends[key]; ok {
+				return tpl, nil
+			}
+
+			if base, ok := withExtends[key]; ok {
+				tpl, err := base.Clone()
+				if err != nil {
+					return nil, errorx.Ef("easytpl: clone the templates for execute failed: %w", err)
+				}
+
+				es.extends[key] = es.prepare(tpl)
+				return es.extends[key], nil
+			}
+		}
+	}
+
+	// find normal template from root
+	tpl := es.root.Lookup(noExt)
+	if tpl == nil && len(noExt) != len(name) {
+		tpl = es.root.Lookup(name)
+	}
+	if tpl != nil {
+		return tpl, nil
+	}
+
+	// maybe loaded after the set created. eg: lazy load by the Loader
+	if tpl, ok := es.extends[name]; ok {
+		return tpl, nil
+	}
+	if master := r.master(name); master != nil {
+		tpl, err := master.Clone()
+		if err != nil {
+			return nil, errorx.Ef("easytpl: clone the templates for execute failed: %w", err)
+		}
+
+		es.extends[name] = es.prepare(tpl)
+		return es.extends[name], nil
+	}
+	return nil, nil
+}
+
+// execute the template by name and write result to w
+func (es *execSet) execute(w io.Writer, name string, v any) error {
+	tpl, err := es.lookup(name)
+	if err != nil {
+		return err
+	}
+	if tpl == nil {
+		return errorx.Ef("easytpl: execute template %q is not found", name)
+	}
+
+	v = es.r.compose(es.st.ctx, name, v)
+	return es.executeTemplate(w, tpl, v)
+}
+
+// execute the template instance and write result to w
+func (es *execSet) executeTemplate(w io.Writer, tpl *template.Template, v any) error {
+	st := es.st
+	st.names = append(st.names, tpl.Tree.Name)
+	defer func() { st.names = st.names[:len(st.names)-1] }()
+
+	es.r.logDebug("easytpl: execute the template", "name", tpl.Tree.Name)
+	return es.executeWriter(w, tpl, v)
+}
+
+// executeWriter execute the template instance with a limitWriter wrapped w.
+func (es *execSet) executeWriter(w io.Writer, tpl *template.Template, v any) error {
+	st := es.st
+	lw := &limitWriter{w: w, st: st}
+	st.writers = append(st.writers, lw)
+	defer func() { st.writers = st.writers[:len(st.writers)-1] }()
+
+	return tpl.Execute(lw, v)
+}
+
+// executeHTML execute the template by name, returns result as HTML. for include and yield.
+func (es *execSet) executeHTML(name string, v any) (template.HTML, error) {
+	if err := es.st.enter(); err != nil {
+		return "", err
+	}
+	defer es.st.leave()
+
+	buf := es.r.bufPool.get()
+	defer es.r.bufPool.put(buf)
+
+	err := es.execute(buf, name, v)
+	return template.HTML(buf.String()), err
+}
+
+// include other template with data.
+//
+// Usage:
+//
+//	{{ include "header" }}
+//	{{ include "header" . }}
+func (es *execSet) include(tplName string, data ...any) (template.HTML, error) {
+	r := es.r
+	// check before lookup, avoid lazy load the not allowed template.
+	if r.Sandbox && !r.allowInclude(tplName) {
+		return "", errorx.Ef("the include template %q is not allowed on sandbox mode", tplName)
+	}
+
+	tpl, err := r.findTemplate(tplName)
+	if err != nil {
+		return "", err
+	}
+	if tpl == nil {
+		return "", errorx.Ef("the include template %q is not found", tplName)
+	}
+
+	// do render template with data
+	var v any
+	if len(data) == 1 {
+		v = data[0]
+	}
+
+	var s template.HTML
+	err = r.track(&RenderEvent{Ctx: es.st.ctx, Name: tplName, Include: true}, func() (n int, err error) {
+		s, err = es.executeHTML(tplName, v)
+		return len(s), err
+	})
+	return s, err
+}
+
+// yield output the rendered inner level content on layout. eg: page or the child layout.
+//
+// With name, will output the named section, fallback to the default content if not defined.
+//
+// Usage:
+//
+//	{{ yield }}
+//	{{ yield "sidebar" }}
+//	{{ yield "title" "Default Title" }}
+func (es *execSet) yield(args ...any) (template.HTML, error) {
+	if len(args) > 0 {
+		name, ok := args[0].(string)
+		if !ok || len(args) > 2 {
+			return "", errorx.E("yield usage: yield [name [default]]")
+		}
+
+		if s, ok := es.st.sections[name]; ok {
+			return s, nil
+		}
+		if len(args) == 2 {
+			// the default value is escaped, unless it is template.HTML
+			if s, ok := args[1].(template.HTML); ok {
+				return s, nil
+			}
+			return template.HTML(template.HTMLEscapeString(fmt.Sprint(args[1]))), nil
+		}
+		return "", nil
+	}
+
+	if !es.st.inLayout {
+		return "", errorx.E("yield called with no layout defined")
+	}
+	return es.st.content, nil
+}
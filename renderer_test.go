@@ -3,6 +3,7 @@ package easytpl_test
 import (
 	"bytes"
 	"fmt"
+	"log/slog"
 	"testing"
 
 	"github.com/gookit/easytpl"
@@ -238,3 +239,85 @@ func TestRenderer_Partial(t *testing.T) {
 	err := r.Partial(bf, "not-exist", nil)
 	is.Error(err)
 }
+
+func TestRenderer_Template_execute(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewInited()
+	r.LoadStrings(map[string]string{
+		"a": `A{{ include "b" }}`,
+		"b": `B`,
+	})
+
+	// execute the template directly, will not affect the renderer
+	is.NoErr(r.Template("a").Execute(bf, nil))
+	is.Eq("AB", bf.String())
+	bf.Reset()
+	is.NoErr(r.Root().ExecuteTemplate(bf, "a", nil))
+	is.Eq("AB", bf.String())
+
+	bf.Reset()
+	is.NoErr(r.Render(bf, "a", nil))
+	is.Eq("AB", bf.String())
+
+	r.LoadString("c", `C{{ include "a" }}`)
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "c", nil))
+	is.Eq("CAB", bf.String())
+}
+
+func TestRenderer_Logger(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+	logBuf := new(bytes.Buffer)
+
+	logger := slog.New(slog.NewJSONHandler(logBuf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	r := easytpl.NewInited(easytpl.WithDebug, easytpl.WithLogger(logger))
+	r.LoadFile("hello", "testdata/hello.tpl")
+	r.LoadString("bad", "{{ .Name.Sub }}")
+
+	is.NoErr(r.Render(bf, "hello", "tom"))
+	is.Err(r.Render(bf, "bad", map[string]string{"Name": "tom"}))
+
+	logs := logBuf.String()
+	is.StrContains(logs, `"level":"DEBUG","msg":"easytpl: load template file","name":"hello","path":"testdata/hello.tpl"`)
+	is.StrContains(logs, `"level":"ERROR","msg":"easytpl: render template failed","name":"bad"`)
+
+	// no debug events on Debug is disabled
+	logBuf.Reset()
+	r = easytpl.NewInited(easytpl.WithLogger(logger))
+	r.LoadFile("hello", "testdata/hello.tpl")
+	is.NoErr(r.Render(bf, "hello", "tom"))
+	is.Empty(logBuf.String())
+}
+
+func TestRenderer_GlobalData(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewInited(easytpl.WithGlobalData(map[string]any{"siteName": "MySite"}))
+	r.SetGlobal("version", "v1.0")
+	r.LoadStrings(map[string]string{
+		"layout": `[{{ global "siteName" }}]{{ yield }}`,
+		"footer": `{{ global "version" }}`,
+		"home":   `{{ $.Global.siteName }} {{ .name }} {{ include "footer" }}`,
+	})
+
+	data := easytpl.M{"name": "tom"}
+	is.NoErr(r.Render(bf, "home", data, "layout"))
+	is.Eq("[MySite]MySite tom v1.0", bf.String())
+	// not modify the render data
+	is.NotContains(data, easytpl.GlobalKey)
+
+	// the render data key wins
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "home", map[string]any{"name": "tom", "Global": map[string]string{"siteName": "Own"}}))
+	is.Eq("Own tom v1.0", bf.String())
+
+	// not a map data
+	bf.Reset()
+	is.NoErr(r.String(bf, `{{ global "siteName" }} {{ . }}`, "tom"))
+	is.Eq("MySite tom", bf.String())
+	is.Eq("v1.0", r.Global("version"))
+}
@@ -0,0 +1,136 @@
+package easytpl
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/gookit/easytpl/tplfunc"
+)
+
+// ValidateError the untrusted template validate error. contains all found problems.
+type ValidateError struct {
+	// Problems list. item like: "string-tpl:2:5: function "env" is not allowed"
+	Problems []string
+}
+
+// Error string
+func (e *ValidateError) Error() string {
+	return "easytpl: validate template failed:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// ValidateString parse an untrusted template text and check it by the sandbox rules, will not execute it.
+//
+// Will report problems:
+//   - parse error of the template text
+//   - use the OS, environment and file access funcs. eg: env, expandenv
+//   - include the templates that not match Options.SandboxIncludes
+//   - call the templates that not defined in the text. eg: {{ template "other" }}
+//
+// Returns *ValidateError on found any disallowed usage.
+func (r *Renderer) ValidateString(tplText string) error {
+	funcs := make(template.FuncMap)
+	for _, fm := range []map[string]any{builtInFuncMap, tplfunc.StdFuncMap(), r.FuncMap, r.includeFuncs()} {
+		for name, fn := range fm {
+			funcs[name] = fn
+		}
+	}
+
+	// parse only, use text/template for skip the html escape process
+	t, err := template.New("string-tpl").
+		Delims(r.Delims.Left, r.Delims.Right).
+		Funcs(funcs).
+		Parse(r.rewriteBlocks(tplText))
+	if err != nil {
+		return &ValidateError{Problems: []string{err.Error()}}
+	}
+
+	var problems []string
+	addProblem := func(tree *parse.Tree, n parse.Node, format string, args ...any) {
+		loc, _ := tree.ErrorContext(n)
+		problems = append(problems, loc+": "+fmt.Sprintf(format, args...))
+	}
+
+	for _, tpl := range t.Templates() {
+		if tpl.Tree == nil {
+			continue
+		}
+
+		tree := tpl.Tree
+		walkTree(tree.Root, func(node parse.Node) bool {
+			switch n := node.(type) {
+			case *parse.IdentifierNode:
+				if _, custom := r.FuncMap[n.Ident]; !custom && tplfunc.IsUnsafeFunc(n.Ident) {
+					addProblem(tree, n, "function %q is not allowed", n.Ident)
+				}
+			case *parse.CommandNode:
+				if isFuncCall(n, "includeFrom") {
+					addProblem(tree, n, "include template from other renderer is not allowed")
+					return true
+				}
+				if !isFuncCall(n, "include", "includeCached") {
+					return true
+				}
+				if name, ok := stringArg(n, 1); !ok {
+					addProblem(tree, n, "include template name must be a constant string")
+				} else if !r.allowInclude(name) {
+					addProblem(tree, n, "include template %q is not allowed", name)
+				}
+			case *parse.TemplateNode:
+				if t.Lookup(n.Name) == nil {
+					addProblem(tree, n, "call template %q is not allowed", n.Name)
+				}
+			}
+			return true
+		})
+	}
+
+	if len(problems) > 0 {
+		return &ValidateError{Problems: problems}
+	}
+	return nil
+}
+
+// checkTemplateCalls check the template text only calls the templates defined in it, or allowed by the func.
+// it is used on load templates on sandbox mode.
+func (r *Renderer) checkTemplateCalls(name, text string, allowed func(name string) bool) error {
+	// parse only, for collect the templates defined in the text
+	pt := parse.New(name)
+	pt.Mode = parse.SkipFuncCheck
+	trees := make(map[string]*parse.Tree)
+	if _, err := pt.Parse(text, r.Delims.Left, r.Delims.Right, trees); err != nil {
+		return err
+	}
+
+	var err error
+	for _, tree := range trees {
+		walkTree(tree.Root, func(node parse.Node) bool {
+			n, ok := node.(*parse.TemplateNode)
+			if !ok || err != nil {
+				return err == nil
+			}
+
+			if _, defined := trees[n.Name]; !defined && (allowed == nil || !allowed(n.Name)) {
+				loc, _ := tree.ErrorContext(n)
+				err = fmt.Errorf("%s: call template %q is not allowed on sandbox mode", loc, n.Name)
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allowInclude check the template name is allowed include on sandbox mode.
+func (r *Renderer) allowInclude(tplName string) bool {
+	name := r.cleanExt(tplName)
+	for _, pattern := range r.SandboxIncludes {
+		if matchTplName(r.cleanExt(pattern), name) {
+			return true
+		}
+	}
+	return false
+}
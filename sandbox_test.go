@@ -0,0 +1,117 @@
+package easytpl_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gookit/easytpl"
+	"github.com/gookit/easytpl/tplfunc"
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+func TestRenderer_Sandbox(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewInited(easytpl.WithSandbox("partials/*"))
+	r.LoadStrings(map[string]string{
+		"partials/footer": `footer:{{.}}`,
+		"secret":          `secret`,
+		"page":            `page, {{ include "partials/footer" . }}`,
+		"page1":           `page1, {{ include "secret" }}`,
+	})
+
+	err := r.Execute(bf, "page", "tom")
+	is.NoErr(err)
+	is.Eq("page, footer:tom", bf.String())
+
+	bf.Reset()
+	err = r.Execute(bf, "page1", "tom")
+	is.ErrSubMsg(err, `the include template "secret" is not allowed`)
+
+	// env func is removed
+	bf.Reset()
+	is.Panics(func() {
+		_ = r.String(bf, `{{ env "HOME" }}`, nil)
+	})
+
+	_, ok := tplfunc.SafeFuncMap()["env"]
+	is.False(ok)
+	_, ok = tplfunc.StdFuncMap()["env"]
+	is.True(ok)
+}
+
+func TestRenderer_Sandbox_lazyLoad(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	l := easytpl.NewMemoryLoader(map[string]string{
+		"page":            `page, {{ include "secret" }}`,
+		"partials/footer": `footer`,
+	})
+	r := easytpl.NewInited(easytpl.WithLoader(l), easytpl.WithSandbox("partials/*"), func(r *easytpl.Renderer) {
+		r.AutoSearchFile = true
+	})
+
+	// the not allowed template is not loaded by the loader
+	l.Set("secret", `secret`)
+	is.ErrSubMsg(r.Execute(bf, "page", nil), `the include template "secret" is not allowed`)
+	is.Nil(r.Template("secret"))
+}
+
+func TestRenderer_Sandbox_templateCalls(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewInited(easytpl.WithSandbox())
+	r.LoadString("secret", `secret`)
+	r.LoadString("page", `{{ define "item" }}item{{ end }}page, {{ template "item" }}`)
+
+	is.NoErr(r.Execute(bf, "page", nil))
+	is.Eq("page, item", bf.String())
+
+	// cannot call the templates not defined in the text
+	is.PanicsMsg(func() {
+		r.LoadString("page1", `page1, {{ template "secret" }}`)
+	}, `easyTpl: [ERROR] page1:1:19: call template "secret" is not allowed on sandbox mode`)
+	is.Nil(r.Template("page1"))
+
+	// extends child can call the templates defined in the base
+	r = easytpl.NewExtends(easytpl.WithSandbox())
+	r.LoadString("base", `{{ block "body" . }}body{{ end }}|{{ define "footer" }}footer{{ end }}`)
+	r.LoadString("home", "{{ extends \"base\" }}\n{{ define \"body\" }}home {{ template \"footer\" }}{{ end }}")
+
+	bf.Reset()
+	is.NoErr(r.Execute(bf, "home", nil))
+	is.Eq("home footer|", bf.String())
+}
+
+func TestRenderer_ValidateString(t *testing.T) {
+	is := assert.New(t)
+	r := easytpl.NewInited(easytpl.WithSandbox("partials/**"))
+
+	is.NoErr(r.ValidateString(`hello {{ .Name | upper }}, {{ include "partials/sub/footer" }}`))
+	is.NoErr(r.ValidateString(`{{ define "item" }}item{{ end }}{{ template "item" }}`))
+
+	err := r.ValidateString(`{{ env "HOME" }}
+{{ include "secret" }}
+{{ template "other" . }}`)
+	is.Err(err)
+	verr, ok := err.(*easytpl.ValidateError)
+	is.True(ok)
+	is.Len(verr.Problems, 3)
+	is.StrContains(verr.Problems[0], `string-tpl:1:3: function "env" is not allowed`)
+	is.StrContains(verr.Problems[1], `include template "secret" is not allowed`)
+	is.StrContains(verr.Problems[2], `call template "other" is not allowed`)
+
+	// dynamic include name
+	err = r.ValidateString(`{{ include .Page }}{{ includeCached (printf "p/%s" .Name) "key" 10 }}`)
+	verr, ok = err.(*easytpl.ValidateError)
+	is.True(ok)
+	is.Len(verr.Problems, 2)
+	is.StrContains(verr.Problems[0], `include template name must be a constant string`)
+
+	// parse error
+	err = r.ValidateString(`{{ notExists }}`)
+	is.ErrSubMsg(err, `function "notExists" not defined`)
+}
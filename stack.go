@@ -0,0 +1,205 @@
+package easytpl
+
+import (
+	"bytes"
+	"html/template"
+	"regexp"
+	"strings"
+	"text/template/parse"
+
+	"github.com/gookit/goutil/errorx"
+)
+
+// endCaptureFuncName the internal func for end capture the push/prepend/section block contents.
+const endCaptureFuncName = "_easytpl_end_capture"
+
+// the block funcs, can be used like: {{ push "scripts" }} ... {{ end }}
+var blockFuncNames = []string{"push", "prepend", "once", "section"}
+
+// capture modes
+const (
+	capturePush uint8 = iota
+	capturePrepend
+	captureSection
+)
+
+// blockRegex build the regex for match the block funcs start. eg: "{{ push "
+func blockRegex(td TplDelims, names []string) *regexp.Regexp {
+	return regexp.MustCompile("(" + regexp.QuoteMeta(td.Left) + `-?\s*)(` + strings.Join(names, "|") + `)(\s)`)
+}
+
+// blockFuncs returns the enabled block func names. the names overridden by Options.FuncMap are skipped.
+func (r *Renderer) blockFuncs() []string {
+	if len(r.FuncMap) == 0 {
+		return blockFuncNames
+	}
+
+	names := make([]string, 0, len(blockFuncNames))
+	for _, name := range blockFuncNames {
+		if _, ok := r.FuncMap[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// rewriteBlocks rewrite the block funcs start to the if statement, so the template can be parsed. like:
+//
+//	{{ push "scripts" }} -> {{ if push "scripts" }}
+func (r *Renderer) rewriteBlocks(text string) string {
+	names := r.blockFuncs()
+
+	var found bool
+	for _, name := range names {
+		if strings.Contains(text, name) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return text
+	}
+
+	re := r.blockRe
+	if re == nil || len(names) != len(blockFuncNames) {
+		re = blockRegex(r.Delims, names)
+	}
+	return re.ReplaceAllString(text, "${1}if ${2}${3}")
+}
+
+// addCaptureEnds append an end capture call to the end of each push/prepend/section block body. like:
+//
+//	{{ if push "scripts" }} ... {{ if _easytpl_end_capture }}{{ end }}{{ end }}
+//
+// NOTE: must call it before the template is executed(escaped).
+func addCaptureEnds(tree *parse.Tree, blockFuncs []string) {
+	var names []string
+	for _, name := range blockFuncs {
+		if name != "once" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+
+	walkTree(tree.Root, func(n parse.Node) bool {
+		in, ok := n.(*parse.IfNode)
+		if !ok || len(in.Pipe.Cmds) != 1 || len(in.Pipe.Decl) > 0 || !isFuncCall(in.Pipe.Cmds[0], names...) {
+			return true
+		}
+
+		if in.List == nil {
+			in.List = &parse.ListNode{NodeType: parse.NodeList, Pos: in.Pos}
+		}
+		in.List.Nodes = append(in.List.Nodes, newCallIf(tree, in.List.Position(), in.Line, endCaptureFuncName))
+		return true
+	})
+}
+
+// capture the contents of a push/prepend/section block
+type capture struct {
+	buf  bytes.Buffer
+	mode uint8
+	// name of the stack or section
+	name string
+}
+
+// startCapture redirect the output of the current writer to a capture buffer.
+func (st *renderState) startCapture(name string, mode uint8) (bool, error) {
+	n := len(st.writers)
+	if n == 0 {
+		return false, errorx.Ef("capture the block %q out of template execution", name)
+	}
+
+	lw := st.writers[n-1]
+	lw.captures = append(lw.captures, &capture{name: name, mode: mode})
+	return true, nil
+}
+
+// endCapture end the current capture, add the contents to the stack or section.
+func (st *renderState) endCapture() (bool, error) {
+	var lw *limitWriter
+	if n := len(st.writers); n > 0 {
+		lw = st.writers[n-1]
+	}
+	if lw == nil || len(lw.captures) == 0 {
+		return false, errorx.E("end capture called without push, prepend or section")
+	}
+
+	c := lw.captures[len(lw.captures)-1]
+	lw.captures = lw.captures[:len(lw.captures)-1]
+
+	op := fragmentOp{Op: "push", Name: c.name, Val: c.buf.String()}
+	switch c.mode {
+	case captureSection:
+		op.Op = "section"
+	case capturePrepend:
+		op.Op = "prepend"
+	}
+
+	st.apply(op)
+	return false, nil
+}
+
+// apply the state change, and record it to the rendering cached fragments.
+func (st *renderState) apply(op fragmentOp) {
+	for _, rec := range st.recs {
+		rec.ops = append(rec.ops, op)
+	}
+
+	switch op.Op {
+	case "section":
+		if st.sections == nil {
+			st.sections = make(map[string]template.HTML)
+		}
+		// the first defined wins, so the page can override the sections of the layouts.
+		if _, ok := st.sections[op.Name]; !ok {
+			st.sections[op.Name] = template.HTML(op.Val)
+		}
+	case "prepend":
+		st.addStack(op.Name, op.Val, true)
+	case "push":
+		st.addStack(op.Name, op.Val, false)
+	case "once":
+		if st.onceKeys == nil {
+			st.onceKeys = make(map[string]bool)
+		}
+		st.onceKeys[op.Name] = true
+	case "set":
+		if st.vars == nil {
+			st.vars = make(map[string]any)
+		}
+		st.vars[op.Name] = op.Val
+	}
+}
+
+func (st *renderState) addStack(name, s string, prepend bool) {
+	if st.stacks == nil {
+		st.stacks = make(map[string][]string)
+	}
+
+	if prepend {
+		st.stacks[name] = append([]string{s}, st.stacks[name]...)
+	} else {
+		st.stacks[name] = append(st.stacks[name], s)
+	}
+}
+
+// once returns true only on the first call with the key in a render.
+func (st *renderState) once(key string) bool {
+	if st.onceKeys == nil {
+		st.onceKeys = make(map[string]bool)
+	}
+	if st.onceKeys[key] {
+		return false
+	}
+
+	st.apply(fragmentOp{Op: "once", Name: key})
+	return true
+}
+
+// stack output all pushed contents of the stack.
+func (st *renderState) stack(name string) template.HTML {
+	return template.HTML(strings.Join(st.stacks[name], ""))
+}
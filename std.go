@@ -35,6 +35,9 @@ func LoadFiles(files ...string) { std.LoadFiles(files...) }
 // LoadByGlob load templates by glob pattern.
 func LoadByGlob(pattern string, baseDirs ...string) { std.LoadByGlob(pattern, baseDirs...) }
 
+// Use add render middlewares to the default instance
+func Use(mws ...Middleware) { std.Use(mws...) }
+
 // Initialize the default instance with config func
 func Initialize(fns ...OptionFn) {
 	std.WithOptions(fns...).MustInit()
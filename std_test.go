@@ -63,9 +63,8 @@ func TestStd_instance(t *testing.T) {
 	is.Contains(str, "home: hello")
 	is.Contains(str, "admin footer")
 
-	is.Panics(func() {
-		_ = r.Render(bf, "home.tpl", "tom", "not-exist.tpl")
-	})
+	err = r.Render(bf, "home.tpl", "tom", "not-exist.tpl")
+	is.ErrSubMsg(err, `the layout template "not-exist.tpl" is not found`)
 
 	r = easytpl.NewInited(func(r *easytpl.Renderer) {
 		r.Layout = "layout"
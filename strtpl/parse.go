@@ -0,0 +1,323 @@
+package strtpl
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// node of the compiled template
+type node interface {
+	// write the node result to the builder
+	render(sb *strings.Builder, data any) error
+}
+
+// textNode plain text
+type textNode string
+
+func (n textNode) render(sb *strings.Builder, _ any) error {
+	sb.WriteString(string(n))
+	return nil
+}
+
+// exprNode a var expression with filters. eg: {{ user.name | upper | default "friend" }}
+type exprNode struct {
+	// raw expression text, for error message
+	raw     string
+	head    operand
+	filters []*filterCall
+}
+
+func (n *exprNode) render(sb *strings.Builder, data any) error {
+	val := n.head.value(data)
+	for _, fc := range n.filters {
+		var err error
+		if val, err = fc.call(data, val); err != nil {
+			return fmt.Errorf("strtpl: render %q error: %w", n.raw, err)
+		}
+	}
+
+	sb.WriteString(toString(val))
+	return nil
+}
+
+// operand of the expression. path or literal value
+type operand interface {
+	value(data any) any
+}
+
+// pathOperand dotted path of the data. eg: user.name, items.0.name
+type pathOperand []string
+
+func (p pathOperand) value(data any) any { return lookupPath(data, p) }
+
+// literal value. eg: "abc", 23, true
+type literal struct{ val any }
+
+func (l literal) value(any) any { return l.val }
+
+// filterCall a filter call with args. eg: default "friend"
+type filterCall struct {
+	name string
+	fn   reflect.Value
+	args []operand
+}
+
+// call the filter func, the piped value will be passed as the last argument.
+func (fc *filterCall) call(data, piped any) (any, error) {
+	args := make([]any, 0, len(fc.args)+1)
+	for _, arg := range fc.args {
+		args = append(args, arg.value(data))
+	}
+	return callFunc(fc.name, fc.fn, append(args, piped))
+}
+
+/*************************************************************
+ * parse the template text
+ *************************************************************/
+
+// parser for parse template text to nodes
+type parser struct {
+	Syntax
+	// find filter func by name
+	filterFn func(name string) (reflect.Value, bool)
+}
+
+// parse the template text to nodes
+func (p *parser) parse(text string) ([]node, error) {
+	var nodes []node
+	var sb strings.Builder // for collect text
+
+	for pos := 0; pos < len(text); {
+		start := strings.Index(text[pos:], p.Prefix)
+		if start < 0 {
+			sb.WriteString(text[pos:])
+			break
+		}
+
+		start += pos
+		exprStart := start + len(p.Prefix)
+
+		// escaped prefix. eg: "\{{" -> "{{"
+		if start > 0 && text[start-1] == '\\' {
+			sb.WriteString(text[pos : start-1])
+			sb.WriteString(p.Prefix)
+			pos = exprStart
+			continue
+		}
+
+		var expr *exprNode
+		var err error
+		var end int
+		if p.Suffix == "" {
+			// no suffix, var name end with non-name char. eg: ":name"
+			end = scanName(text, exprStart)
+			if end == exprStart {
+				sb.WriteString(text[pos:exprStart])
+				pos = exprStart
+				continue
+			}
+			expr, err = p.parseExpr(text[exprStart:end])
+		} else {
+			if end = indexSuffix(text, exprStart, p.Suffix); end < 0 {
+				return nil, fmt.Errorf("strtpl: unclosed var at %d, missing %q", start, p.Suffix)
+			}
+			expr, err = p.parseExpr(text[exprStart:end])
+			end += len(p.Suffix)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("strtpl: parse var at %d: %w", start, err)
+		}
+
+		sb.WriteString(text[pos:start])
+		if sb.Len() > 0 {
+			nodes = append(nodes, textNode(sb.String()))
+			sb.Reset()
+		}
+
+		nodes = append(nodes, expr)
+		pos = end
+	}
+
+	if sb.Len() > 0 {
+		nodes = append(nodes, textNode(sb.String()))
+	}
+	return nodes, nil
+}
+
+// indexSuffix find the suffix position from pos, will skip the quoted string.
+func indexSuffix(text string, pos int, suffix string) int {
+	var quote byte
+	for i := pos; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case strings.HasPrefix(text[i:], suffix):
+			return i
+		case c == '"' || c == '\'':
+			quote = c
+		}
+	}
+	return -1
+}
+
+// scanName scan the var name from pos, returns the end position. name chars: [a-zA-Z0-9_.]
+func scanName(text string, pos int) int {
+	i := pos
+	for ; i < len(text); i++ {
+		c := text[i]
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+			continue
+		}
+		// digit and "." cannot be first char
+		if i > pos && (c == '.' || (c >= '0' && c <= '9')) {
+			continue
+		}
+		break
+	}
+
+	// trim the last "." eg: "Hi :name."
+	for i > pos && text[i-1] == '.' {
+		i--
+	}
+	return i
+}
+
+// parseExpr parse var expression. eg: "user.name | upper | default 'friend'"
+func (p *parser) parseExpr(raw string) (*exprNode, error) {
+	tokens, err := splitTokens(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 || tokens[0] == "|" {
+		return nil, fmt.Errorf("empty var expression %q", raw)
+	}
+
+	n := &exprNode{raw: strings.TrimSpace(raw)}
+	var fc *filterCall
+	for i, tok := range tokens {
+		if tok == "|" {
+			if i+1 >= len(tokens) || tokens[i+1] == "|" {
+				return nil, fmt.Errorf("missing filter name after '|' in %q", raw)
+			}
+			fc = nil
+			continue
+		}
+
+		// head operand
+		if i == 0 {
+			if n.head, err = parseOperand(tok); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// filter name
+		if tokens[i-1] == "|" {
+			fn, ok := p.filterFn(tok)
+			if !ok {
+				return nil, fmt.Errorf("filter %q is not defined", tok)
+			}
+
+			fc = &filterCall{name: tok, fn: fn}
+			n.filters = append(n.filters, fc)
+			continue
+		}
+
+		if fc == nil {
+			return nil, fmt.Errorf("unexpected %q in %q, missing '|'", tok, raw)
+		}
+
+		// filter args
+		arg, err := parseOperand(tok)
+		if err != nil {
+			return nil, err
+		}
+		fc.args = append(fc.args, arg)
+	}
+	return n, nil
+}
+
+// splitTokens split expression by space and '|', keep the quoted string.
+func splitTokens(s string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '|':
+			tokens = append(tokens, "|")
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			for ; end < len(s) && s[end] != c; end++ {
+				if s[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated quoted string in %q", s)
+			}
+			tokens = append(tokens, s[i:end+1])
+			i = end + 1
+		default:
+			end := i
+			for ; end < len(s) && !strings.ContainsRune(" \t\r\n|", rune(s[end])); end++ {
+			}
+			tokens = append(tokens, s[i:end])
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+// parseOperand parse token to literal or path operand.
+func parseOperand(tok string) (operand, error) {
+	switch c := tok[0]; {
+	case c == '"':
+		s, err := strconv.Unquote(tok)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted string %s", tok)
+		}
+		return literal{s}, nil
+	case c == '\'':
+		return literal{strings.ReplaceAll(tok[1:len(tok)-1], `\'`, "'")}, nil
+	case c == '-' || c == '+' || (c >= '0' && c <= '9'):
+		if iv, err := strconv.ParseInt(tok, 10, 64); err == nil {
+			return literal{int(iv)}, nil
+		}
+		if fv, err := strconv.ParseFloat(tok, 64); err == nil {
+			return literal{fv}, nil
+		}
+		return nil, fmt.Errorf("invalid number %q", tok)
+	}
+
+	switch tok {
+	case "true", "false":
+		return literal{tok == "true"}, nil
+	case "nil":
+		return literal{nil}, nil
+	}
+
+	// "." is the data self
+	path := strings.TrimPrefix(tok, ".")
+	if path == "" {
+		return pathOperand(nil), nil
+	}
+
+	keys := strings.Split(path, ".")
+	for _, key := range keys {
+		if key == "" {
+			return nil, fmt.Errorf("invalid var path %q", tok)
+		}
+	}
+	return pathOperand(keys), nil
+}
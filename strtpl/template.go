@@ -1,20 +1,490 @@
+// Package strtpl provides a lightweight string template engine, for render short texts. eg: SMS, push notification.
+//
+//   - access value by dotted path, support map, struct and slice index. eg: {{ user.name }}, {{ items.0.name }}
+//   - chained filters with args. the piped value will be passed as the last argument, same as text/template.
+//   - all funcs in the tplfunc.SafeFuncMap() can be used as filter, and support add custom filters.
+//   - extra built-in filters: default, empty, coalesce
+//
+// Example:
+//
+//	Hi {{ user.name | upper | default "friend" }}, your order {{ order.id }} is shipped.
 package strtpl
 
 import (
+	"container/list"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gookit/easytpl/tplfunc"
+	"github.com/gookit/goutil"
+	"github.com/gookit/goutil/reflects"
+	"github.com/gookit/goutil/strutil"
 	"github.com/gookit/goutil/strutil/textutil"
 )
 
-// StrTemplate implement a simple string template.
-// Alias of textutil.LiteTemplate
+// DefaultCacheSize the default max number of compiled templates cached by text.
+const DefaultCacheSize = 256
+
+// StrTemplate implement a lightweight string template engine.
+//
+// Compile a text once, then the compiled Template can be rendered many times concurrently.
 type StrTemplate struct {
+	// LiteTemplate keep for compatible.
+	//
+	// Deprecated: the options of it are not used on render, please use Syntax and AddFilter() instead.
 	textutil.LiteTemplate
+
+	// Syntax the var placeholder syntax. default is DoubleBrace: {{ name }}
+	Syntax Syntax
+	// CacheSize the max number of compiled templates cached by text. default is DefaultCacheSize.
+	//
+	// Set to negative for disable the cache.
+	CacheSize int
+
+	mu sync.RWMutex
+	// custom filters. key is filter name.
+	filters map[string]reflect.Value
+	// compiled templates LRU cache. key is template text.
+	lru   *list.List
+	cache map[string]*list.Element
+}
+
+// Syntax the var placeholder syntax, is a prefix and suffix pair.
+//
+// Suffix can be empty, then the var name is end with non-name char. eg: ":name"
+//
+// Use a backslash before the prefix for write literal prefix. eg: `\{{ name }}` -> "{{ name }}"
+type Syntax struct {
+	Prefix, Suffix string
+}
+
+// built-in placeholder syntaxes
+var (
+	// DoubleBrace syntax: {{ name }}
+	DoubleBrace = Syntax{Prefix: "{{", Suffix: "}}"}
+	// SingleBrace syntax: {name}
+	SingleBrace = Syntax{Prefix: "{", Suffix: "}"}
+	// DollarBrace syntax: ${name}
+	DollarBrace = Syntax{Prefix: "${", Suffix: "}"}
+	// Colon syntax: :name. NOTE: not support filters
+	Colon = Syntax{Prefix: ":"}
+	// Percent syntax: %name%
+	Percent = Syntax{Prefix: "%", Suffix: "%"}
+)
+
+// WithSyntax set the var placeholder syntax
+//
+// Usage:
+//
+//	st := strtpl.NewStrTemplate(strtpl.WithSyntax(strtpl.DollarBrace))
+//	st := strtpl.NewStrTemplate(strtpl.WithSyntax(strtpl.Syntax{Prefix: "[[", Suffix: "]]"}))
+func WithSyntax(syn Syntax) func(st *StrTemplate) {
+	return func(st *StrTemplate) { st.Syntax = syn }
+}
+
+// WithCacheSize set the max number of compiled templates cached by text. negative for disable the cache.
+func WithCacheSize(size int) func(st *StrTemplate) {
+	return func(st *StrTemplate) { st.CacheSize = size }
 }
 
 // NewStrTemplate instance
 func NewStrTemplate(opFns ...func(st *StrTemplate)) *StrTemplate {
-	st := &StrTemplate{}
+	st := &StrTemplate{
+		Syntax:    DoubleBrace,
+		CacheSize: DefaultCacheSize,
+		filters:   make(map[string]reflect.Value),
+		lru:       list.New(),
+		cache:     make(map[string]*list.Element),
+	}
+
 	for _, fn := range opFns {
 		fn(st)
 	}
 	return st
 }
+
+// AddFilter add a custom filter func. will panic on fn is not a func.
+//
+// The func allow return 1 or 2 values, if return 2 values, the second value must be error.
+//
+// Usage:
+//
+//	st.AddFilter("mask", func(s string) string { ... })
+//	st.Render(`{{ phone | mask }}`, data)
+func (st *StrTemplate) AddFilter(name string, fn any) {
+	rv := reflect.ValueOf(fn)
+	if rv.Kind() != reflect.Func {
+		panic(fmt.Sprintf("strtpl: the filter %q must be a func", name))
+	}
+	if err := reflects.OneOrTwoOutChecker(rv.Type()); err != nil {
+		panic(fmt.Sprintf("strtpl: the filter %q is invalid: %s", name, err))
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.filters[name] = rv
+	// filters changed, clear compiled caches
+	st.lru.Init()
+	st.cache = make(map[string]*list.Element)
+}
+
+// AddFilters add multi custom filter funcs.
+func (st *StrTemplate) AddFilters(fns map[string]any) {
+	for name, fn := range fns {
+		st.AddFilter(name, fn)
+	}
+}
+
+// AddFuncs add multi custom filter funcs.
+//
+// Deprecated: please use AddFilters() instead.
+func (st *StrTemplate) AddFuncs(fns map[string]any) { st.AddFilters(fns) }
+
+// Compile the template text, the returned Template can be rendered concurrently.
+func (st *StrTemplate) Compile(text string) (*Template, error) {
+	if st.Syntax.Prefix == "" {
+		return nil, fmt.Errorf("strtpl: the placeholder prefix is required")
+	}
+
+	p := &parser{Syntax: st.Syntax, filterFn: st.filterFn}
+	nodes, err := p.parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{text: text, nodes: nodes}, nil
+}
+
+// MustCompile the template text, will panic on error.
+func (st *StrTemplate) MustCompile(text string) *Template {
+	t, err := st.Compile(text)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Render the template text with data. the compiled template will be cached.
+func (st *StrTemplate) Render(text string, data any) (string, error) {
+	t, err := st.cached(text)
+	if err != nil {
+		return "", err
+	}
+	return t.Render(data)
+}
+
+// RenderString render the template text with data, returns empty string on error.
+func (st *StrTemplate) RenderString(text string, data any) string {
+	s, _ := st.Render(text, data)
+	return s
+}
+
+// RenderWrite render the template text with data, and write result to the writer.
+func (st *StrTemplate) RenderWrite(w io.Writer, text string, data any) error {
+	t, err := st.cached(text)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, data)
+}
+
+// RenderFile read the template file and render it with data.
+func (st *StrTemplate) RenderFile(filePath string, data any) (string, error) {
+	bs, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	return st.Render(string(bs), data)
+}
+
+// cached get the compiled template from the LRU cache, compile and cache it on not found.
+func (st *StrTemplate) cached(text string) (*Template, error) {
+	if st.CacheSize < 0 {
+		return st.Compile(text)
+	}
+
+	st.mu.Lock()
+	if el, ok := st.cache[text]; ok {
+		st.lru.MoveToFront(el)
+		st.mu.Unlock()
+		return el.Value.(*Template), nil
+	}
+	st.mu.Unlock()
+
+	t, err := st.Compile(text)
+	if err != nil {
+		return nil, err
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.cache[text]; !ok {
+		st.cache[text] = st.lru.PushFront(t)
+	}
+
+	size := st.CacheSize
+	if size == 0 {
+		size = DefaultCacheSize
+	}
+	for st.lru.Len() > size {
+		el := st.lru.Back()
+		st.lru.Remove(el)
+		delete(st.cache, el.Value.(*Template).text)
+	}
+	return t, nil
+}
+
+// Vars parse the template text and returns all referenced var names. eg: ["user.name", "city"]
+//
+// It is useful for validate a template and list required vars before saving it.
+func (st *StrTemplate) Vars(text string) ([]string, error) {
+	t, err := st.cached(text)
+	if err != nil {
+		return nil, err
+	}
+	return t.Vars(), nil
+}
+
+// filterFn find filter func by name. find custom filters first, then find on the built-in filters.
+func (st *StrTemplate) filterFn(name string) (reflect.Value, bool) {
+	st.mu.RLock()
+	rv, ok := st.filters[name]
+	st.mu.RUnlock()
+	if ok {
+		return rv, true
+	}
+
+	if fn, ok := stdFilters[name]; ok {
+		return reflect.ValueOf(fn), true
+	}
+	return reflect.Value{}, false
+}
+
+// stdFilters the built-in filters, contains the safe funcs from the tplfunc package.
+//
+// The funcs can access OS or environment are not included, can be added by AddFilter(). eg: env
+var stdFilters = tplfunc.SafeFuncMap()
+
+func init() {
+	stdFilters["default"] = dfault
+	stdFilters["empty"] = goutil.IsEmpty
+	stdFilters["coalesce"] = coalesce
+}
+
+/*************************************************************
+ * compiled template
+ *************************************************************/
+
+// Template a compiled string template. it is immutable, can be rendered concurrently.
+type Template struct {
+	text  string
+	nodes []node
+}
+
+// Text returns the source text of the template
+func (t *Template) Text() string { return t.text }
+
+// Vars returns all referenced var names of the template, in order of first appearance.
+func (t *Template) Vars() []string {
+	var names []string
+	exists := make(map[string]bool)
+	addVar := func(op operand) {
+		if path, ok := op.(pathOperand); ok && len(path) > 0 {
+			name := strings.Join(path, ".")
+			if !exists[name] {
+				exists[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	for _, n := range t.nodes {
+		expr, ok := n.(*exprNode)
+		if !ok {
+			continue
+		}
+
+		addVar(expr.head)
+		for _, fc := range expr.filters {
+			for _, arg := range fc.args {
+				addVar(arg)
+			}
+		}
+	}
+	return names
+}
+
+// Render the template with data, returns the result string.
+func (t *Template) Render(data any) (string, error) {
+	var sb strings.Builder
+	sb.Grow(len(t.text))
+
+	for _, n := range t.nodes {
+		if err := n.render(&sb, data); err != nil {
+			return "", err
+		}
+	}
+	return sb.String(), nil
+}
+
+// Execute render the template with data, and write result to the writer.
+func (t *Template) Execute(w io.Writer, data any) error {
+	s, err := t.Render(data)
+	if err == nil {
+		_, err = io.WriteString(w, s)
+	}
+	return err
+}
+
+/*************************************************************
+ * std instance
+ *************************************************************/
+
+var std = NewStrTemplate()
+
+// Std get the default StrTemplate instance
+func Std() *StrTemplate { return std }
+
+// Render the template text with data by the default instance.
+func Render(text string, data any) (string, error) { return std.Render(text, data) }
+
+// RenderString render the template text with data by the default instance, returns empty string on error.
+func RenderString(text string, data any) string { return std.RenderString(text, data) }
+
+/*************************************************************
+ * helper functions
+ *************************************************************/
+
+// lookupPath get value from data by path keys. returns nil on not found.
+func lookupPath(data any, keys []string) any {
+	val := data
+	for _, key := range keys {
+		rv := reflect.ValueOf(val)
+		for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+			if rv.IsNil() {
+				return nil
+			}
+			rv = rv.Elem()
+		}
+
+		var sub reflect.Value
+		switch rv.Kind() {
+		case reflect.Map:
+			if rv.Type().Key().Kind() != reflect.String {
+				return nil
+			}
+			sub = rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+		case reflect.Struct:
+			sub = rv.FieldByName(key)
+			if !sub.IsValid() {
+				sub = rv.FieldByName(strutil.UpFirst(key))
+			}
+			// unexported field
+			if sub.IsValid() && !sub.CanInterface() {
+				return nil
+			}
+		case reflect.Slice, reflect.Array:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= rv.Len() {
+				return nil
+			}
+			sub = rv.Index(idx)
+		}
+
+		if !sub.IsValid() {
+			return nil
+		}
+		val = sub.Interface()
+	}
+	return val
+}
+
+// callFunc call the filter func with args, will try to convert args to func args type.
+func callFunc(name string, fn reflect.Value, args []any) (any, error) {
+	typ := fn.Type()
+	numIn := typ.NumIn()
+	if typ.IsVariadic() {
+		if len(args) < numIn-1 {
+			return nil, fmt.Errorf("filter %q wrong number of args: got %d want at least %d", name, len(args), numIn-1)
+		}
+	} else if len(args) != numIn {
+		return nil, fmt.Errorf("filter %q wrong number of args: got %d want %d", name, len(args), numIn)
+	}
+
+	argv := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		argType := typ.In(min(i, numIn-1))
+		if typ.IsVariadic() && i >= numIn-1 {
+			argType = argType.Elem()
+		}
+
+		rv, err := convArg(arg, argType)
+		if err != nil {
+			return nil, fmt.Errorf("filter %q arg %d: %w", name, i, err)
+		}
+		argv[i] = rv
+	}
+
+	ret := fn.Call(argv)
+	if len(ret) == 2 && !ret[1].IsNil() {
+		return nil, ret[1].Interface().(error)
+	}
+	return ret[0].Interface(), nil
+}
+
+// convArg convert the arg to the func arg type. nil will be converted to zero value.
+func convArg(arg any, typ reflect.Type) (reflect.Value, error) {
+	if arg == nil {
+		return reflect.Zero(typ), nil
+	}
+
+	rv := reflect.ValueOf(arg)
+	if rv.Type().AssignableTo(typ) {
+		return rv, nil
+	}
+	return reflects.ValueByType(arg, typ)
+}
+
+// dfault returns the given value if it is not empty, otherwise returns the default value.
+//
+// Usage:
+//
+//	{{ name | default "guest" }}
+func dfault(def any, given ...any) any {
+	if len(given) == 0 || goutil.IsEmpty(given[0]) {
+		return def
+	}
+	return given[0]
+}
+
+// coalesce returns the first not empty value.
+func coalesce(vs ...any) any {
+	for _, v := range vs {
+		if !goutil.IsEmpty(v) {
+			return v
+		}
+	}
+	return nil
+}
+
+// toString convert value to string for output. nil will be converted to empty string.
+func toString(val any) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	s, err := strutil.ToString(val)
+	if err != nil {
+		return fmt.Sprint(val)
+	}
+	return s
+}
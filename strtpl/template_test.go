@@ -0,0 +1,189 @@
+package strtpl_test
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gookit/easytpl/strtpl"
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+type user struct {
+	Name string
+	Tags []string
+}
+
+func TestStrTemplate_Render(t *testing.T) {
+	st := strtpl.NewStrTemplate()
+	data := map[string]any{
+		"user":  &user{Name: "tom", Tags: []string{"a", "b"}},
+		"items": []map[string]any{{"name": "item0"}},
+		"city":  "",
+	}
+
+	tests := []struct{ tpl, want string }{
+		{"hi {{ user.name }}", "hi tom"},
+		{"hi {{ .user.Name | upper }}", "hi TOM"},
+		{"hi {{ user.Tags.1 }}, {{ items.0.name }}", "hi b, item0"},
+		{"hi {{ user.age }}", "hi "},
+		{`hi {{ guest.name | upper | default "friend" }}`, "hi friend"},
+		{`city: {{ city | default 'unknown' }}`, "city: unknown"},
+		{`name: {{ "  tom " | trim | ucFirst }}`, "name: Tom"},
+		{`{{ "tom" | ucFirst }} {{ 23 }}`, "Tom 23"},
+	}
+
+	for _, tt := range tests {
+		s, err := st.Render(tt.tpl, data)
+		assert.NoErr(t, err, tt.tpl)
+		assert.Eq(t, tt.want, s, tt.tpl)
+	}
+
+	assert.Eq(t, "hi tom", strtpl.RenderString("hi {{ name }}", map[string]string{"name": "tom"}))
+}
+
+func TestStrTemplate_Compile_error(t *testing.T) {
+	st := strtpl.NewStrTemplate()
+
+	tests := []struct{ tpl, err string }{
+		{"hi {{ name ", "unclosed var"},
+		{"hi {{ }}", "empty var expression"},
+		{"hi {{ name | notExist }}", `filter "notExist" is not defined`},
+		{"hi {{ name | }}", "missing filter name"},
+		{"hi {{ name other }}", `unexpected "other"`},
+		{`hi {{ name | default "abc }}`, "unclosed var"},
+		{`hi {{ user..name }}`, "invalid var path"},
+	}
+
+	for _, tt := range tests {
+		_, err := st.Compile(tt.tpl)
+		assert.ErrSubMsg(t, err, tt.err, tt.tpl)
+	}
+
+	assert.Panics(t, func() {
+		st.MustCompile("hi {{ name ")
+	})
+}
+
+func TestStrTemplate_AddFilter(t *testing.T) {
+	st := strtpl.NewStrTemplate()
+	st.AddFilter("mask", func(s string) string {
+		if len(s) < 4 {
+			return s
+		}
+		return strings.Repeat("*", len(s)-4) + s[len(s)-4:]
+	})
+	st.AddFilters(map[string]any{
+		"wrap": func(l, r, s string) string { return l + s + r },
+	})
+
+	tpl := st.MustCompile(`phone: {{ phone | mask | wrap "[" "]" }}`)
+	assert.Eq(t, `phone: {{ phone | mask | wrap "[" "]" }}`, tpl.Text())
+
+	// render concurrently
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, err := tpl.Render(map[string]any{"phone": "13800001234"})
+			assert.NoErr(t, err)
+			assert.Eq(t, "phone: [*******1234]", s)
+		}()
+	}
+	wg.Wait()
+
+	_, err := st.Render(`{{ phone | wrap "[" }}`, map[string]any{"phone": "123"})
+	assert.ErrSubMsg(t, err, "wrong number of args")
+
+	assert.Panics(t, func() {
+		st.AddFilter("invalid", "not-func")
+	})
+}
+
+func TestStrTemplate_unsafeFilters(t *testing.T) {
+	t.Setenv("STRTPL_SECRET", "secret")
+
+	// the env funcs are not built-in filters
+	st := strtpl.NewStrTemplate()
+	_, err := st.Render(`{{ "STRTPL_SECRET" | env }}`, nil)
+	assert.ErrSubMsg(t, err, `filter "env" is not defined`)
+	_, err = strtpl.Render(`{{ "$STRTPL_SECRET" | expandenv }}`, nil)
+	assert.ErrSubMsg(t, err, `filter "expandenv" is not defined`)
+
+	// add it by AddFilter
+	st.AddFilter("env", os.Getenv)
+	s, err := st.Render(`{{ "STRTPL_SECRET" | env }}`, nil)
+	assert.NoErr(t, err)
+	assert.Eq(t, "secret", s)
+}
+
+func TestStrTemplate_Syntax(t *testing.T) {
+	data := map[string]any{"name": "tom", "user": map[string]any{"age": 23}}
+
+	tests := []struct {
+		syn       strtpl.Syntax
+		tpl, want string
+	}{
+		{strtpl.DoubleBrace, `hi {{name}}, \{{ name }} {{ name | default "}}" }}`, "hi tom, {{ name }} tom"},
+		{strtpl.SingleBrace, `hi {name}, age {user.age}, \{name}`, "hi tom, age 23, {name}"},
+		{strtpl.DollarBrace, `hi ${ name | upper }, {name} $name`, "hi TOM, {name} $name"},
+		{strtpl.Colon, `hi :name, age :user.age. at 10:30, \:name`, "hi tom, age 23. at 10:30, :name"},
+		{strtpl.Percent, `hi %name%, 100\% sure`, "hi tom, 100% sure"},
+		{strtpl.Syntax{Prefix: "[[", Suffix: "]]"}, `hi [[ name ]]`, "hi tom"},
+	}
+
+	for _, tt := range tests {
+		st := strtpl.NewStrTemplate(strtpl.WithSyntax(tt.syn))
+		s, err := st.Render(tt.tpl, data)
+		assert.NoErr(t, err, tt.tpl)
+		assert.Eq(t, tt.want, s, tt.tpl)
+	}
+
+	st := strtpl.NewStrTemplate(strtpl.WithSyntax(strtpl.Syntax{}))
+	_, err := st.Compile("hi")
+	assert.ErrSubMsg(t, err, "prefix is required")
+}
+
+func TestStrTemplate_Vars(t *testing.T) {
+	st := strtpl.NewStrTemplate()
+	vars, err := st.Vars(`hi {{ user.name | default guest }}, {{ city }}, {{ "abc" }} {{ user.name }}`)
+	assert.NoErr(t, err)
+	assert.Eq(t, []string{"user.name", "guest", "city"}, vars)
+
+	_, err = st.Vars(`hi {{ user.name `)
+	assert.Err(t, err)
+
+	st = strtpl.NewStrTemplate(strtpl.WithSyntax(strtpl.Colon))
+	vars, err = st.Vars(`hi :name, order :order.id.`)
+	assert.NoErr(t, err)
+	assert.Eq(t, []string{"name", "order.id"}, vars)
+}
+
+func TestStrTemplate_cache(t *testing.T) {
+	for _, size := range []int{1, -1} {
+		st := strtpl.NewStrTemplate(strtpl.WithCacheSize(size))
+		for i := 0; i < 3; i++ {
+			for _, name := range []string{"tom", "john"} {
+				s, err := st.Render("hi {{ name }}, {{ name | upper }}", map[string]any{"name": name})
+				assert.NoErr(t, err)
+				assert.Eq(t, "hi "+name+", "+strings.ToUpper(name), s)
+				assert.Eq(t, "by "+name, st.RenderString("by {{ name }}", map[string]any{"name": name}))
+			}
+		}
+	}
+}
+
+func TestStrTemplate_compatible(t *testing.T) {
+	st := strtpl.NewStrTemplate()
+	st.AddFuncs(map[string]any{"mask": func(s string) string { return "***" }})
+	assert.Eq(t, "phone: ***", st.RenderString("phone: {{ phone | mask }}", map[string]any{"phone": "123"}))
+
+	s, err := st.RenderFile("../testdata/hello.tpl", map[string]any{"name": "tom"})
+	assert.NoErr(t, err)
+	assert.NotEmpty(t, s)
+
+	_, err = st.RenderFile("../testdata/not-exist.tpl", nil)
+	assert.Err(t, err)
+}
@@ -44,6 +44,29 @@ func StdFuncMap() template.FuncMap {
 	return stdFuncMap
 }
 
+// SafeFuncMap returns the template func map without OS, environment and file access funcs.
+// It is for render untrusted templates.
+func SafeFuncMap() template.FuncMap {
+	fm := make(template.FuncMap, len(stdFuncMap))
+	for name, fn := range stdFuncMap {
+		if !IsUnsafeFunc(name) {
+			fm[name] = fn
+		}
+	}
+	return fm
+}
+
+// unsafeFuncNames funcs can access OS, environment or files.
+var unsafeFuncNames = map[string]bool{
+	"env":       true,
+	"expandenv": true,
+}
+
+// IsUnsafeFunc check the func can access OS, environment or files.
+func IsUnsafeFunc(name string) bool {
+	return unsafeFuncNames[name]
+}
+
 // stdFuncMap is the default template func map.
 var stdFuncMap = map[string]any{
 	// String:
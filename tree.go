@@ -0,0 +1,111 @@
+package easytpl
+
+import (
+	"text/template/parse"
+)
+
+// walkTree walk all nodes of the parse tree, will stop walk into children on fn return false.
+func walkTree(node parse.Node, fn func(n parse.Node) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		for _, sub := range n.Nodes {
+			walkTree(sub, fn)
+		}
+	case *parse.ActionNode:
+		walkTree(n.Pipe, fn)
+	case *parse.IfNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.RangeNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.WithNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.TemplateNode:
+		if n.Pipe != nil {
+			walkTree(n.Pipe, fn)
+		}
+	case *parse.PipeNode:
+		for _, cmd := range n.Cmds {
+			walkTree(cmd, fn)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			walkTree(arg, fn)
+		}
+	case *parse.ChainNode:
+		walkTree(n.Node, fn)
+	}
+}
+
+func walkBranch(n *parse.BranchNode, fn func(n parse.Node) bool) {
+	walkTree(n.Pipe, fn)
+	if n.List != nil {
+		walkTree(n.List, fn)
+	}
+	if n.ElseList != nil {
+		walkTree(n.ElseList, fn)
+	}
+}
+
+// newCallIf create an if node that call the func and has empty body. like:
+//
+//	{{ if funcName }}{{ end }}
+func newCallIf(tree *parse.Tree, pos parse.Pos, line int, funcName string) *parse.IfNode {
+	ident := parse.NewIdentifier(funcName).SetTree(tree).SetPos(pos)
+	return &parse.IfNode{BranchNode: parse.BranchNode{
+		NodeType: parse.NodeIf,
+		Pos:      pos,
+		Line:     line,
+		Pipe: &parse.PipeNode{
+			NodeType: parse.NodePipe,
+			Pos:      pos,
+			Line:     line,
+			Cmds: []*parse.CommandNode{{
+				NodeType: parse.NodeCommand,
+				Pos:      pos,
+				Args:     []parse.Node{ident},
+			}},
+		},
+		List: &parse.ListNode{NodeType: parse.NodeList, Pos: pos},
+	}}
+}
+
+// prepareTree add the internal calls to the tree before execute. see addRangeTicks, addCaptureEnds
+func prepareTree(tree *parse.Tree, blockFuncs []string) {
+	addRangeTicks(tree)
+	addCaptureEnds(tree, blockFuncs)
+}
+
+// isFuncCall check the command is call the named func. eg: {{ include "name" }}
+func isFuncCall(cmd *parse.CommandNode, names ...string) bool {
+	if len(cmd.Args) == 0 {
+		return false
+	}
+
+	ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+	if !ok {
+		return false
+	}
+
+	for _, name := range names {
+		if ident.Ident == name {
+			return true
+		}
+	}
+	return false
+}
+
+// stringArg get string value of the command argument at index. ok is false on not a string constant.
+func stringArg(cmd *parse.CommandNode, index int) (string, bool) {
+	if index >= len(cmd.Args) {
+		return "", false
+	}
+
+	if sn, ok := cmd.Args[index].(*parse.StringNode); ok {
+		return sn.Text, true
+	}
+	return "", false
+}
@@ -3,6 +3,8 @@ package easytpl
 import (
 	"bytes"
 	"fmt"
+	"path"
+	"strings"
 	"sync"
 )
 
@@ -71,3 +73,22 @@ func (bp bufferPool) put(buf *bytes.Buffer) {
 	buf.Reset()
 	bp.p.Put(buf)
 }
+
+// matchTplName check the template name is match the pattern.
+//
+// Pattern:
+//   - exact name: "partials/footer"
+//   - glob pattern: "partials/*", see path.Match()
+//   - namespace: "partials/**" - match all names under "partials/"
+func matchTplName(pattern, name string) bool {
+	if pattern == name {
+		return true
+	}
+
+	if strings.HasSuffix(pattern, "/**") {
+		return strings.HasPrefix(name, pattern[:len(pattern)-2])
+	}
+
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
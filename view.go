@@ -0,0 +1,141 @@
+package easytpl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"text/template/parse"
+
+	"github.com/gookit/goutil/errorx"
+)
+
+// TypedView a template bound to the data type T. create by View()
+type TypedView[T any] struct {
+	r    *Renderer
+	name string
+}
+
+// View create a typed view for the template, the render data must be type T.
+//
+// It will check every data field path used in the template(and the called templates
+// by {{ template }}, {{ block }}) exists on T, returns *ValidateError on found missing fields.
+//
+// Usage:
+//
+//	view, err := easytpl.View[ProfileData](r, "user/profile")
+//	err = view.Render(w, ProfileData{...})
+func View[T any](r *Renderer, name string) (*TypedView[T], error) {
+	tpl := r.master(name)
+	if tpl == nil {
+		return nil, errorx.Ef("easytpl: the template %q is not found", name)
+	}
+
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Interface {
+		w := &refWalker{lookup: func(name string) *parse.Tree {
+			if t := tpl.Lookup(name); t != nil {
+				return t.Tree
+			}
+			return nil
+		}}
+		w.walkTree(tpl.Tree, []string{})
+
+		var problems []string
+		seen := make(map[string]bool)
+		for _, ref := range w.refs {
+			if msg := checkDataPath(typ, ref.path); msg != "" {
+				loc, _ := ref.tree.ErrorContext(ref.node)
+				if problem := loc + ": " + msg; !seen[problem] {
+					seen[problem] = true
+					problems = append(problems, problem)
+				}
+			}
+		}
+
+		if len(problems) > 0 {
+			return nil, &ValidateError{Problems: problems}
+		}
+	}
+
+	return &TypedView[T]{r: r, name: name}, nil
+}
+
+// MustView create a typed view for the template, will panic on error.
+func MustView[T any](r *Renderer, name string) *TypedView[T] {
+	v, err := View[T](r, name)
+	panicErr(err)
+	return v
+}
+
+// Name of the template
+func (v *TypedView[T]) Name() string { return v.name }
+
+// Render the template with layout. see Renderer.Render()
+func (v *TypedView[T]) Render(w io.Writer, data T, layout ...string) error {
+	return v.r.Render(w, v.name, data, layout...)
+}
+
+// RenderContext render the template with layout and context. see Renderer.RenderContext()
+func (v *TypedView[T]) RenderContext(ctx context.Context, w io.Writer, data T, layout ...string) error {
+	return v.r.RenderContext(ctx, w, v.name, data, layout...)
+}
+
+// Execute render the template without layout. see Renderer.Execute()
+func (v *TypedView[T]) Execute(w io.Writer, data T) error {
+	return v.r.Execute(w, v.name, data)
+}
+
+// checkDataPath check the data path exists on the type, returns problem message on not exists.
+func checkDataPath(typ reflect.Type, path []string) string {
+	for i, seg := range path {
+		for typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+
+		// unknown type, eg: any
+		if typ.Kind() == reflect.Interface {
+			return ""
+		}
+
+		if seg == rangeElem {
+			switch typ.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+				typ = typ.Elem()
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Func:
+				// range over int or iterator func
+				return ""
+			default:
+				return fmt.Sprintf("cannot range over %s (type %s)", pathString(path[:i]), typ)
+			}
+			continue
+		}
+
+		// method call. eg: .User.FullName
+		if m, ok := reflect.PointerTo(typ).MethodByName(seg); ok {
+			if m.Type.NumOut() == 0 {
+				return ""
+			}
+			typ = m.Type.Out(0)
+			continue
+		}
+
+		switch typ.Kind() {
+		case reflect.Struct:
+			field, ok := typ.FieldByName(seg)
+			if !ok || !field.IsExported() {
+				return fmt.Sprintf("field %s not found on type %s", pathString(path[:i+1]), typ)
+			}
+			typ = field.Type
+		case reflect.Map:
+			if typ.Key().Kind() != reflect.String {
+				return fmt.Sprintf("cannot access field %s on type %s", pathString(path[:i+1]), typ)
+			}
+			typ = typ.Elem()
+		default:
+			return fmt.Sprintf("cannot access field %s on type %s", pathString(path[:i+1]), typ)
+		}
+	}
+	return ""
+}
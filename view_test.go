@@ -0,0 +1,70 @@
+package easytpl_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/gookit/easytpl"
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+type profileUser struct {
+	Name string
+	Tags []string
+	Meta map[string]any
+}
+
+func (u *profileUser) Title() string { return "Mr. " + u.Name }
+
+type profileData struct {
+	User   *profileUser
+	Orders []struct{ ID int }
+	secret string
+}
+
+func TestView(t *testing.T) {
+	is := assert.New(t)
+	bf := new(bytes.Buffer)
+
+	r := easytpl.NewInited()
+	r.LoadStrings(map[string]string{
+		"user/profile": `{{ define "orders" }}{{ range . }}#{{ .ID }}{{ end }}{{ end -}}
+{{ with .User }}{{ .Title }}: {{ range .Tags }}{{ . }},{{ end }}{{ .Meta.any.thing }}{{ end }}
+{{- $u := .User }}{{ $u.Name }} {{ template "orders" .Orders }}{{ len $.Orders }}`,
+		"user/bad": `{{ .User.Nmae }}{{ with .User }}{{ range .Name }}{{ end }}{{ end }}
+{{ range $i, $o := .Orders }}{{ $o.Price }}{{ end }}{{ .secret }}{{ .User.Name.Len }}`,
+	})
+
+	view, err := easytpl.View[profileData](r, "user/profile")
+	is.NoErr(err)
+	is.Eq("user/profile", view.Name())
+
+	data := profileData{
+		User:   &profileUser{Name: "tom", Tags: []string{"a", "b"}},
+		Orders: []struct{ ID int }{{ID: 1}, {ID: 2}},
+	}
+	is.NoErr(view.Execute(bf, data))
+	is.Eq("Mr. tom: a,b,tom #1#22", bf.String())
+
+	_, err = easytpl.View[profileData](r, "user/bad")
+	var ve *easytpl.ValidateError
+	is.True(errors.As(err, &ve))
+	is.Len(ve.Problems, 5)
+	is.StrContains(ve.Problems[0], "user/bad:1:8: field .User.Nmae not found on type easytpl_test.profileUser")
+	is.StrContains(err.Error(), "cannot range over .User.Name (type string)")
+	is.StrContains(err.Error(), "field .Orders[].Price not found")
+	is.StrContains(err.Error(), "field .secret not found")
+	is.StrContains(err.Error(), "cannot access field .User.Name.Len on type string")
+
+	// not found
+	_, err = easytpl.View[profileData](r, "not-exist")
+	is.ErrSubMsg(err, `the template "not-exist" is not found`)
+	is.Panics(func() {
+		easytpl.MustView[profileData](r, "user/bad")
+	})
+
+	// any type will skip check
+	anyView := easytpl.MustView[any](r, "user/bad")
+	is.NotNil(anyView)
+}